@@ -0,0 +1,210 @@
+// Package apitest provides a mock api.Client, for testing code that uses the client.
+package apitest
+
+import (
+	"context"
+
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/gojekfarm/albatross-client-go/chart"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/jsonschema"
+	"github.com/gojekfarm/albatross-client-go/manifest"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/gojekfarm/albatross-client-go/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+// Client is a mock api.Client. Every method records the call with all its arguments
+// except ctx, in the order of the interface, and returns what was set up with On:
+//
+//	client := new(apitest.Client)
+//	client.On("Status", "api", mock.Anything).Return(release.Release{Status: "deployed"}, nil)
+//
+// Flags are recorded as they are passed. Missing return values are returned as zero values.
+// Clients returned by ForCluster and InNamespace share the calls and expectations of the
+// client they were created from, so expectations are set up on the client created first.
+// They fill in the kube context or namespace they are bound to when the flags leave it empty
+type Client struct {
+	mock.Mock
+
+	root     *Client
+	defaults flags.CommonFlags
+}
+
+var _ api.Client = &Client{}
+
+// called records a call on the client the expectations are set up on
+func (c *Client) called(method string, args ...interface{}) mock.Arguments {
+	if c.root != nil {
+		return c.root.MethodCalled(method, args...)
+	}
+	return c.MethodCalled(method, args...)
+}
+
+func result(args mock.Arguments) api.Result {
+	r, _ := args.Get(0).(api.Result)
+	return r
+}
+
+func rel(args mock.Arguments) release.Release {
+	r, _ := args.Get(0).(release.Release)
+	return r
+}
+
+func releases(args mock.Arguments) []release.Release {
+	r, _ := args.Get(0).([]release.Release)
+	return r
+}
+
+// err returns the error at the index, if the expectation returns one
+func err(args mock.Arguments, index int) error {
+	if len(args) <= index {
+		return nil
+	}
+	return args.Error(index)
+}
+
+func (c *Client) List(ctx context.Context, fl flags.ListFlags) ([]release.Release, error) {
+	fl.CommonFlags = c.bind(fl.CommonFlags)
+	args := c.called("List", fl)
+	return releases(args), err(args, 1)
+}
+
+func (c *Client) Install(ctx context.Context, name string, chart string, values api.Values, fl flags.InstallFlags) (api.Result, error) {
+	fl.CommonFlags = c.bind(fl.CommonFlags)
+	args := c.called("Install", name, chart, values, fl)
+	return result(args), err(args, 1)
+}
+
+func (c *Client) Upgrade(ctx context.Context, name string, chart string, values api.Values, fl flags.UpgradeFlags) (api.Result, error) {
+	fl.CommonFlags = c.bind(fl.CommonFlags)
+	args := c.called("Upgrade", name, chart, values, fl)
+	return result(args), err(args, 1)
+}
+
+func (c *Client) Status(ctx context.Context, name string, fl flags.StatusFlags) (release.Release, error) {
+	fl.CommonFlags = c.bind(fl.CommonFlags)
+	args := c.called("Status", name, fl)
+	return rel(args), err(args, 1)
+}
+
+func (c *Client) GetValues(ctx context.Context, name string, fl flags.GetValuesFlags) (api.Values, error) {
+	fl.CommonFlags = c.bind(fl.CommonFlags)
+	args := c.called("GetValues", name, fl)
+	values, _ := args.Get(0).(api.Values)
+	return values, err(args, 1)
+}
+
+func (c *Client) Uninstall(ctx context.Context, name string, fl flags.UninstallFlags) (release.Release, error) {
+	fl.CommonFlags = c.bind(fl.CommonFlags)
+	args := c.called("Uninstall", name, fl)
+	return rel(args), err(args, 1)
+}
+
+func (c *Client) History(ctx context.Context, name string, fl flags.HistoryFlags) ([]release.Release, error) {
+	fl.CommonFlags = c.bind(fl.CommonFlags)
+	args := c.called("History", name, fl)
+	return releases(args), err(args, 1)
+}
+
+func (c *Client) Rollback(ctx context.Context, name string, fl flags.RollbackFlags) (release.Release, error) {
+	fl.CommonFlags = c.bind(fl.CommonFlags)
+	args := c.called("Rollback", name, fl)
+	return rel(args), err(args, 1)
+}
+
+func (c *Client) AddRepo(ctx context.Context, name string, url string, fl flags.AddRepoFlags) (repository.Repository, error) {
+	args := c.called("AddRepo", name, url, fl)
+	repo, _ := args.Get(0).(repository.Repository)
+	return repo, err(args, 1)
+}
+
+func (c *Client) ListRepos(ctx context.Context) ([]repository.Repository, error) {
+	args := c.called("ListRepos")
+	repos, _ := args.Get(0).([]repository.Repository)
+	return repos, err(args, 1)
+}
+
+func (c *Client) UpdateRepos(ctx context.Context, fl flags.UpdateRepoFlags) error {
+	return err(c.called("UpdateRepos", fl), 0)
+}
+
+func (c *Client) RemoveRepo(ctx context.Context, name string) error {
+	return err(c.called("RemoveRepo", name), 0)
+}
+
+func (c *Client) SearchCharts(ctx context.Context, query string, fl flags.SearchFlags) ([]chart.Chart, error) {
+	args := c.called("SearchCharts", query, fl)
+	charts, _ := args.Get(0).([]chart.Chart)
+	return charts, err(args, 1)
+}
+
+func (c *Client) ListChartVersions(ctx context.Context, chartName string) ([]chart.Chart, error) {
+	args := c.called("ListChartVersions", chartName)
+	charts, _ := args.Get(0).([]chart.Chart)
+	return charts, err(args, 1)
+}
+
+func (c *Client) Template(ctx context.Context, name string, chart string, values api.Values, fl flags.TemplateFlags) ([]manifest.Document, error) {
+	if fl.Namespace == "" {
+		fl.Namespace = c.defaults.Namespace
+	}
+	args := c.called("Template", name, chart, values, fl)
+	documents, _ := args.Get(0).([]manifest.Document)
+	return documents, err(args, 1)
+}
+
+// Test shadows mock.Mock.Test, which sets the testing.T used to report failures.
+// It is still available as client.Mock.Test(t)
+func (c *Client) Test(ctx context.Context, name string, fl flags.TestFlags) ([]release.TestResult, error) {
+	fl.CommonFlags = c.bind(fl.CommonFlags)
+	args := c.called("Test", name, fl)
+	results, _ := args.Get(0).([]release.TestResult)
+	return results, err(args, 1)
+}
+
+func (c *Client) ValuesSchema(ctx context.Context, chartName string, version string) (*jsonschema.Schema, error) {
+	args := c.called("ValuesSchema", chartName, version)
+	schema, _ := args.Get(0).(*jsonschema.Schema)
+	return schema, err(args, 1)
+}
+
+// ForCluster returns a client that fills in the kube context, sharing the expectations of c
+func (c *Client) ForCluster(kubeContext string) api.Client {
+	return c.scoped(func(defaults *flags.CommonFlags) { defaults.KubeContext = kubeContext })
+}
+
+// InNamespace returns a client that fills in the namespace, sharing the expectations of c
+func (c *Client) InNamespace(namespace string) api.Client {
+	return c.scoped(func(defaults *flags.CommonFlags) { defaults.Namespace = namespace })
+}
+
+func (c *Client) scoped(set func(defaults *flags.CommonFlags)) *Client {
+	scoped := &Client{root: c.root, defaults: c.defaults}
+	if scoped.root == nil {
+		scoped.root = c
+	}
+	set(&scoped.defaults)
+	return scoped
+}
+
+// Scope returns the common flags as the client sends them, bound to its kube context and
+// namespace, and with the default namespace if neither sets one. It is not recorded
+func (c *Client) Scope(fl flags.CommonFlags) flags.CommonFlags {
+	fl = c.bind(fl)
+	if fl.Namespace == "" {
+		fl.Namespace = flags.DefaultNamespace
+	}
+	return fl
+}
+
+// bind fills the kube context and namespace left empty with those the client is bound to
+func (c *Client) bind(fl flags.CommonFlags) flags.CommonFlags {
+	if fl.KubeContext == "" {
+		fl.KubeContext = c.defaults.KubeContext
+	}
+	if fl.Namespace == "" {
+		fl.Namespace = c.defaults.Namespace
+	}
+	return fl
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/gojekfarm/albatross-client-go/chart"
+	"github.com/gojekfarm/albatross-client-go/flags"
+)
+
+// ApplyResult is returned by Apply
+type ApplyResult struct {
+	Result
+
+	// Changed reports whether the release was installed or upgraded
+	Changed bool
+
+	// Reason explains why the release was changed
+	Reason string
+
+	// Diff holds the changes from the deployed values to the applied values
+	Diff []ValueChange
+}
+
+// Apply installs a release if it does not exist, and upgrades it only when the chart,
+// the chart version or the values differ from the deployed release, so that applying
+// the same release again does not create a new revision. A version constraint is
+// resolved to the highest matching chart version before comparing. Without a version,
+// the deployed version is kept as long as the chart is the same.
+// With ReuseValues, the values are compared after coalescing them with the deployed values
+func Apply(ctx context.Context, client Client, name string, chartName string, values Values, fl flags.UpgradeFlags) (ApplyResult, error) {
+	current, err := client.Status(ctx, name, flags.StatusFlags{CommonFlags: fl.CommonFlags})
+	if errors.Is(err, ErrReleaseNotFound) {
+		fl.Install = true
+		return upgrade(ctx, client, name, chartName, values, fl, ApplyResult{Reason: "release is not installed"})
+	}
+	if err != nil {
+		return ApplyResult{}, err
+	}
+
+	if flags.IsVersionConstraint(fl.Version) {
+		versions, err := client.ListChartVersions(ctx, chartName)
+		if err != nil {
+			return ApplyResult{}, fmt.Errorf("Error resolving version %q of chart %s: %s", fl.Version, chartName, err)
+		}
+		latest, err := chart.Latest(versions, fl.Version)
+		if err != nil {
+			return ApplyResult{}, fmt.Errorf("Error resolving version %q of chart %s: %s", fl.Version, chartName, err)
+		}
+		fl.Version = latest.Version
+	}
+
+	deployed, err := client.GetValues(ctx, name, flags.GetValuesFlags{CommonFlags: fl.CommonFlags})
+	if err != nil {
+		return ApplyResult{}, err
+	}
+	desired := values.Merge()
+	if fl.ReuseValues {
+		desired = deployed.Coalesce(values)
+	}
+	result := ApplyResult{Diff: deployed.Diff(desired)}
+
+	// releases report their chart as <name>-<version>
+	deployedName, deployedVersion, _ := chart.SplitNameVersion(current.Chart)
+	sameChart := deployedName == path.Base(chartName)
+	if fl.Version == "" && sameChart {
+		fl.Version = deployedVersion
+	}
+	switch {
+	case current.Status != "deployed":
+		result.Reason = fmt.Sprintf("release is %s", current.Status)
+	case !sameChart:
+		result.Reason = fmt.Sprintf("chart changed from %s", current.Chart)
+	case deployedVersion != fl.Version:
+		result.Reason = fmt.Sprintf("chart version changed from %s", deployedVersion)
+	case len(result.Diff) > 0:
+		result.Reason = "values changed"
+	default:
+		result.Result = Result{Status: current.Status, Version: deployedVersion}
+		return result, nil
+	}
+	return upgrade(ctx, client, name, chartName, values, fl, result)
+}
+
+func upgrade(ctx context.Context, client Client, name string, chartName string, values Values, fl flags.UpgradeFlags, result ApplyResult) (ApplyResult, error) {
+	upgraded, err := client.Upgrade(ctx, name, chartName, values, fl)
+	if err != nil {
+		return ApplyResult{}, err
+	}
+	result.Result = upgraded
+	result.Changed = true
+	return result, nil
+}
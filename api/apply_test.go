@@ -0,0 +1,194 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gojekfarm/albatross-client-go/chart"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	applyReleaseURL = "http://localhost:8080/clusters/staging/namespaces/default/releases/api"
+	applyValuesURL  = applyReleaseURL + "/values"
+)
+
+func mockDeployedRelease(t *testing.T, apiclient *mockAPIClient, rel release.Release, values Values) {
+	statusResponse, statusData := jsonResponse(t, 200, &statusResponse{Release: rel})
+	apiclient.On("Send", applyReleaseURL, http.MethodGet, nil).Return(statusResponse, statusData, nil)
+	valuesResponse, valuesData := jsonResponse(t, 200, &getValuesResponse{Values: values})
+	apiclient.On("Send", applyValuesURL, http.MethodGet, nil).Return(valuesResponse, valuesData, nil)
+}
+
+func mockUpgrade(t *testing.T, apiclient *mockAPIClient) {
+	httpresponse, apiresponse := jsonResponse(t, 200, &upgradeResponse{Status: "deployed"})
+	apiclient.On("Send", applyReleaseURL, http.MethodPut, mock.Anything).Return(httpresponse, apiresponse, nil).Once()
+}
+
+func sentUpgradeRequest(t *testing.T, apiclient *mockAPIClient) upgradeRequest {
+	for _, call := range apiclient.Calls {
+		if call.Arguments.String(1) == http.MethodPut {
+			var req upgradeRequest
+			require.NoError(t, json.Unmarshal(call.Arguments.Get(2).(*bytes.Buffer).Bytes(), &req))
+			return req
+		}
+	}
+	t.Fatal("no upgrade request sent")
+	return upgradeRequest{}
+}
+
+func TestApplyInstallsMissingRelease(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	apiclient.On("Send", applyReleaseURL, http.MethodGet, nil).Return(&http.Response{StatusCode: 404}, nil, nil)
+	mockUpgrade(t, apiclient)
+
+	result, err := Apply(context.Background(), newTestHttpClient(apiclient), "api", "stable/api", Values{"replicas": 2}, flags.UpgradeFlags{
+		Version:     "1.2.0",
+		CommonFlags: flags.CommonFlags{KubeContext: "staging"},
+	})
+
+	require.NoError(t, err)
+	assert.True(t, result.Changed)
+	assert.Equal(t, "release is not installed", result.Reason)
+	assert.Equal(t, "deployed", result.Status)
+	assert.True(t, sentUpgradeRequest(t, apiclient).Flags.Install)
+	apiclient.AssertExpectations(t)
+}
+
+func TestApplySkipsUpgradeWhenNothingChanged(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	mockDeployedRelease(t, apiclient, release.Release{Name: "api", Status: "deployed", Chart: "api-1.2.0"},
+		Values{"replicas": 2, "image": Values{"tag": "1.0"}})
+
+	result, err := Apply(context.Background(), newTestHttpClient(apiclient), "api", "stable/api",
+		Values{"image": map[interface{}]interface{}{"tag": "1.0"}, "replicas": 2},
+		flags.UpgradeFlags{Version: "1.2.0", CommonFlags: flags.CommonFlags{KubeContext: "staging"}})
+
+	require.NoError(t, err)
+	assert.False(t, result.Changed)
+	assert.Empty(t, result.Diff)
+	assert.Equal(t, Result{Status: "deployed", Version: "1.2.0"}, result.Result)
+	apiclient.AssertNotCalled(t, "Send", applyReleaseURL, http.MethodPut, mock.Anything)
+}
+
+func TestApplyUpgradesChangedRelease(t *testing.T) {
+	testCases := []struct {
+		name    string
+		release release.Release
+		values  Values
+		flags   flags.UpgradeFlags
+		reason  string
+		diff    []ValueChange
+	}{
+		{
+			name:    "values changed",
+			release: release.Release{Status: "deployed", Chart: "api-1.2.0"},
+			values:  Values{"replicas": 3},
+			flags:   flags.UpgradeFlags{Version: "1.2.0"},
+			reason:  "values changed",
+			diff:    []ValueChange{{Type: ValueChanged, Path: "replicas", Old: float64(2), New: 3}},
+		},
+		{
+			name:    "chart version changed",
+			release: release.Release{Status: "deployed", Chart: "api-1.1.0"},
+			values:  Values{"replicas": 2},
+			flags:   flags.UpgradeFlags{Version: "1.2.0"},
+			reason:  "chart version changed from 1.1.0",
+		},
+		{
+			name:    "chart changed",
+			release: release.Release{Status: "deployed", Chart: "legacy-api-1.2.0"},
+			values:  Values{"replicas": 2},
+			flags:   flags.UpgradeFlags{Version: "1.2.0"},
+			reason:  "chart changed from legacy-api-1.2.0",
+		},
+		{
+			name:    "chart name is a prefix of the deployed chart",
+			release: release.Release{Status: "deployed", Chart: "api-gateway-1.2.0"},
+			values:  Values{"replicas": 2},
+			flags:   flags.UpgradeFlags{Version: "1.2.0"},
+			reason:  "chart changed from api-gateway-1.2.0",
+		},
+		{
+			name:    "release failed",
+			release: release.Release{Status: "failed", Chart: "api-1.2.0"},
+			values:  Values{"replicas": 2},
+			flags:   flags.UpgradeFlags{Version: "1.2.0"},
+			reason:  "release is failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiclient := new(mockAPIClient)
+			mockDeployedRelease(t, apiclient, tc.release, Values{"replicas": 2})
+			mockUpgrade(t, apiclient)
+			tc.flags.CommonFlags = flags.CommonFlags{KubeContext: "staging"}
+
+			result, err := Apply(context.Background(), newTestHttpClient(apiclient), "api", "stable/api", tc.values, tc.flags)
+
+			require.NoError(t, err)
+			assert.True(t, result.Changed)
+			assert.Equal(t, tc.reason, result.Reason)
+			assert.Equal(t, tc.diff, result.Diff)
+			assert.False(t, sentUpgradeRequest(t, apiclient).Flags.Install)
+			apiclient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplyKeepsTheDeployedVersionWithoutAVersion(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	mockDeployedRelease(t, apiclient, release.Release{Status: "deployed", Chart: "api-1.2.0-rc.1"}, Values{"replicas": 2})
+	mockUpgrade(t, apiclient)
+
+	result, err := Apply(context.Background(), newTestHttpClient(apiclient), "api", "stable/api", Values{"replicas": 3}, flags.UpgradeFlags{
+		CommonFlags: flags.CommonFlags{KubeContext: "staging"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "values changed", result.Reason)
+	assert.Equal(t, "1.2.0-rc.1", sentUpgradeRequest(t, apiclient).Flags.Version)
+}
+
+func TestApplyResolvesVersionConstraintBeforeComparing(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	mockDeployedRelease(t, apiclient, release.Release{Status: "deployed", Chart: "api-1.2.0"}, Values{})
+	versionsResponse, versionsData := jsonResponse(t, 200, &chartsResponse{Charts: []chart.Chart{
+		{Name: "stable/api", Version: "1.2.0"},
+		{Name: "stable/api", Version: "1.3.0"},
+		{Name: "stable/api", Version: "2.0.0"},
+	}})
+	apiclient.On("Send", "http://localhost:8080/charts/stable/api/versions", http.MethodGet, nil).Return(versionsResponse, versionsData, nil)
+	mockUpgrade(t, apiclient)
+
+	result, err := Apply(context.Background(), newTestHttpClient(apiclient), "api", "stable/api", nil, flags.UpgradeFlags{
+		Version:     "^1.2",
+		CommonFlags: flags.CommonFlags{KubeContext: "staging"},
+	})
+
+	require.NoError(t, err)
+	assert.True(t, result.Changed)
+	assert.Equal(t, "chart version changed from 1.2.0", result.Reason)
+	assert.Equal(t, "1.3.0", sentUpgradeRequest(t, apiclient).Flags.Version)
+}
+
+func TestApplyComparesMergedValuesWhenReusingValues(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	mockDeployedRelease(t, apiclient, release.Release{Status: "deployed", Chart: "api-1.2.0"}, Values{"replicas": 2, "debug": true})
+
+	result, err := Apply(context.Background(), newTestHttpClient(apiclient), "api", "stable/api", Values{"replicas": 2}, flags.UpgradeFlags{
+		ReuseValues: true,
+		CommonFlags: flags.CommonFlags{KubeContext: "staging"},
+	})
+
+	require.NoError(t, err)
+	assert.False(t, result.Changed)
+}
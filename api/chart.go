@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gojekfarm/albatross-client-go/chart"
+	"github.com/gojekfarm/albatross-client-go/flags"
+)
+
+// chartsResponse is the json schema to parse the chart search and chart versions api responses
+type chartsResponse struct {
+	Error  string        `json:"error,omitempty"`
+	Charts []chart.Chart `json:"charts,omitempty"`
+}
+
+// SearchCharts calls the chart search api and filters the result by the version constraint
+func (c *HttpClient) SearchCharts(ctx context.Context, query string, fl flags.SearchFlags) ([]chart.Chart, error) {
+	if err := fl.Valid(); err != nil {
+		return nil, err
+	}
+
+	queryParams := url.Values{}
+	if err := encoder.Encode(fl, queryParams); err != nil {
+		return nil, err
+	}
+	if query != "" {
+		queryParams.Set("query", query)
+	}
+	if fl.VersionConstraint != "" {
+		// All versions are needed to pick the ones matching the constraint
+		queryParams.Set("versions", "true")
+	}
+
+	_, charts, err := c.charts(ctx, "/charts", queryParams.Encode(), "SearchCharts")
+	if err != nil || fl.VersionConstraint == "" {
+		return charts, err
+	}
+	return chart.Filter(charts, fl.VersionConstraint)
+}
+
+// ListChartVersions calls the chart versions api
+func (c *HttpClient) ListChartVersions(ctx context.Context, chartName string) ([]chart.Chart, error) {
+	if chartName == "" {
+		return nil, errors.New("chart cannot be empty")
+	}
+
+	reqPath := fmt.Sprintf("/charts/%s/versions", chartName)
+	httpResponse, charts, err := c.charts(ctx, reqPath, "", "ListChartVersions")
+	if err != nil {
+		return nil, err
+	}
+	if httpResponse.StatusCode == 404 {
+		return nil, fmt.Errorf("no chart found: %s", chartName)
+	}
+
+	chart.Sort(charts)
+	return charts, nil
+}
+
+// charts sends a request to one of the chart apis and parses the charts in the response.
+// The response of a 404 is returned without parsing the body
+func (c *HttpClient) charts(ctx context.Context, reqPath string, queryString string, apiName string) (*http.Response, []chart.Chart, error) {
+	httpResponse, data, err := c.request(ctx, flags.CommonFlags{}, reqPath, http.MethodGet, nil, queryString)
+	if err != nil {
+		return nil, nil, err
+	}
+	if httpResponse.StatusCode == 204 || httpResponse.StatusCode == 404 {
+		return httpResponse, []chart.Chart{}, nil
+	}
+
+	var result chartsResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, nil, err
+	}
+
+	if result.Error != "" {
+		return nil, nil, fmt.Errorf("%s API returned an error: %s", apiName, result.Error)
+	}
+
+	return httpResponse, result.Charts, nil
+}
+
+// resolveVersion returns the highest chart version matching a version constraint.
+// Concrete and empty versions are returned as they are
+func (c *HttpClient) resolveVersion(ctx context.Context, chartName string, version string) (string, error) {
+	if !flags.IsVersionConstraint(version) {
+		return version, nil
+	}
+
+	versions, err := c.ListChartVersions(ctx, chartName)
+	if err != nil {
+		return "", fmt.Errorf("Error resolving version %q of chart %s: %s", version, chartName, err)
+	}
+	latest, err := chart.Latest(versions, version)
+	if err != nil {
+		return "", fmt.Errorf("Error resolving version %q of chart %s: %s", version, chartName, err)
+	}
+	return latest.Version, nil
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gojekfarm/albatross-client-go/chart"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testChartVersions = []chart.Chart{
+	{Name: "stable/nginx", Version: "1.2.0", AppVersion: "1.19", Description: "nginx"},
+	{Name: "stable/nginx", Version: "1.3.0", AppVersion: "1.20", Description: "nginx"},
+	{Name: "stable/nginx", Version: "1.2.3", AppVersion: "1.19", Description: "nginx", Deprecated: true},
+}
+
+func TestHttpClientSearchChartsAPIOnSuccess(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 200, &chartsResponse{Charts: testChartVersions[1:2]})
+	apiclient.On("Send", "http://localhost:8080/charts?devel=true&query=nginx", http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+	charts, err := newTestHttpClient(apiclient).SearchCharts(context.Background(), "nginx", flags.SearchFlags{Devel: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, testChartVersions[1:2], charts)
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientSearchChartsAPIWithVersionConstraint(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 200, &chartsResponse{Charts: testChartVersions})
+	apiclient.On("Send", "http://localhost:8080/charts?query=nginx&versions=true", http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+	charts, err := newTestHttpClient(apiclient).SearchCharts(context.Background(), "nginx", flags.SearchFlags{VersionConstraint: "~1.2"})
+
+	assert.NoError(t, err)
+	assert.Len(t, charts, 2)
+	assert.Equal(t, "1.2.3", charts[0].Version)
+	assert.True(t, charts[0].Deprecated)
+	assert.Equal(t, "1.2.0", charts[1].Version)
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientSearchChartsAPIOnFailure(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 500, &chartsResponse{Error: "index not loaded"})
+	apiclient.On("Send", mock.Anything, http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+	_, err := newTestHttpClient(apiclient).SearchCharts(context.Background(), "nginx", flags.SearchFlags{})
+
+	assert.EqualError(t, err, "SearchCharts API returned an error: index not loaded")
+}
+
+func TestHttpClientSearchChartsAPIRejectsInvalidConstraint(t *testing.T) {
+	apiclient := new(mockAPIClient)
+
+	_, err := newTestHttpClient(apiclient).SearchCharts(context.Background(), "nginx", flags.SearchFlags{VersionConstraint: "~>>1"})
+
+	assert.Error(t, err)
+	apiclient.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHttpClientListChartVersionsAPI(t *testing.T) {
+	t.Run("When the chart exists", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 200, &chartsResponse{Charts: testChartVersions})
+		apiclient.On("Send", "http://localhost:8080/charts/stable/nginx/versions", http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+		charts, err := newTestHttpClient(apiclient).ListChartVersions(context.Background(), "stable/nginx")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1.3.0", "1.2.3", "1.2.0"}, []string{charts[0].Version, charts[1].Version, charts[2].Version})
+	})
+
+	t.Run("When the chart does not exist", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		apiclient.On("Send", "http://localhost:8080/charts/stable/unknown/versions", http.MethodGet, nil).Return(&http.Response{StatusCode: 404}, nil, nil)
+
+		_, err := newTestHttpClient(apiclient).ListChartVersions(context.Background(), "stable/unknown")
+
+		assert.EqualError(t, err, "no chart found: stable/unknown")
+	})
+
+	t.Run("When the chart is empty", func(t *testing.T) {
+		_, err := newTestHttpClient(new(mockAPIClient)).ListChartVersions(context.Background(), "")
+		assert.EqualError(t, err, "chart cannot be empty")
+	})
+}
+
+func TestHttpClientInstallAPIResolvesVersionConstraint(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	versionsResponse, versionsData := jsonResponse(t, 200, &chartsResponse{Charts: testChartVersions})
+	apiclient.On("Send", "http://localhost:8080/charts/stable/nginx/versions", http.MethodGet, nil).Return(versionsResponse, versionsData, nil).Once()
+	installResponse, installData := jsonResponse(t, 200, &installResponse{Status: "deployed"})
+	fl := flags.InstallFlags{
+		Version:     "~1.2",
+		CommonFlags: flags.CommonFlags{KubeContext: "staging", Namespace: "apps"},
+	}
+	resolved := fl
+	resolved.Version = "1.2.3"
+	expectedReq, err := json.Marshal(&installRequest{Chart: "stable/nginx", Values: Values{}, Flags: installFlags{InstallFlags: resolved}, Name: "nginx"})
+	require.NoError(t, err)
+	apiclient.On("Send", "http://localhost:8080/clusters/staging/namespaces/apps/releases", http.MethodPost, bytes.NewBuffer(expectedReq)).Return(installResponse, installData, nil).Once()
+
+	result, err := newTestHttpClient(apiclient).Install(context.Background(), "nginx", "stable/nginx", Values{}, fl)
+
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Status: "deployed", Version: "1.2.3"}, result)
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientUpgradeAPIFailsWhenNoVersionSatisfiesConstraint(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	versionsResponse, versionsData := jsonResponse(t, 200, &chartsResponse{Charts: testChartVersions})
+	apiclient.On("Send", "http://localhost:8080/charts/stable/nginx/versions", http.MethodGet, nil).Return(versionsResponse, versionsData, nil).Once()
+	fl := flags.UpgradeFlags{
+		Version:     ">=2",
+		CommonFlags: flags.CommonFlags{KubeContext: "staging", Namespace: "apps"},
+	}
+
+	_, err := newTestHttpClient(apiclient).Upgrade(context.Background(), "nginx", "stable/nginx", Values{}, fl)
+
+	assert.EqualError(t, err, `Error resolving version ">=2" of chart stable/nginx: no chart version satisfies the constraint ">=2"`)
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientUpgradeAPIRejectsInvalidVersionConstraint(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	fl := flags.UpgradeFlags{
+		Version:     "~>>1",
+		CommonFlags: flags.CommonFlags{KubeContext: "staging", Namespace: "apps"},
+	}
+
+	_, err := newTestHttpClient(apiclient).Upgrade(context.Background(), "nginx", "stable/nginx", Values{}, fl)
+
+	assert.Error(t, err)
+	apiclient.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -4,50 +4,162 @@ import (
 	"context"
 	"net/url"
 
+	"github.com/gojekfarm/albatross-client-go/chart"
 	"github.com/gojekfarm/albatross-client-go/config"
 	"github.com/gojekfarm/albatross-client-go/flags"
 	"github.com/gojekfarm/albatross-client-go/httpclient"
+	"github.com/gojekfarm/albatross-client-go/jsonschema"
+	"github.com/gojekfarm/albatross-client-go/manifest"
 	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/gojekfarm/albatross-client-go/repository"
 )
 
 // Values represents the chart values that need to be overriden
 type Values map[string]interface{}
 
+// Result is returned by the install and upgrade apis
+type Result struct {
+	Status string
+
+	// Version is the chart version sent to the server. If the flags held a version
+	// constraint, it is the version the constraint was resolved to
+	Version string
+}
+
 // Client represents a contract that a concrete client types(http/grpc) must implement
 type Client interface {
 	// List returns a list of release corresponding to the provided list flags
 	List(ctx context.Context, fl flags.ListFlags) ([]release.Release, error)
 
-	// Install installs a release, specified by the params, and returns a status
+	// Install installs a release, specified by the params, and returns the status and chart version.
+	// A version constraint in the flags is resolved to the highest matching chart version first
 	// TODO: We should have the api return the release object, instead of just the status
-	Install(ctx context.Context, name string, chart string, values Values, fl flags.InstallFlags) (string, error)
+	Install(ctx context.Context, name string, chart string, values Values, fl flags.InstallFlags) (Result, error)
 
-	// Upgrade installs a release, specified by the params, and returns a status.
-	// UpgradeFlags govern the actions of upgrade action, i.e whether it should be installed if not present
-	Upgrade(ctx context.Context, name string, chart string, values Values, fl flags.UpgradeFlags) (string, error)
+	// Upgrade installs a release, specified by the params, and returns the status and chart version.
+	// UpgradeFlags govern the actions of upgrade action, i.e whether it should be installed if not present.
+	// A version constraint in the flags is resolved to the highest matching chart version first
+	Upgrade(ctx context.Context, name string, chart string, values Values, fl flags.UpgradeFlags) (Result, error)
 
 	// Status returns the status of a release with the specific release and revision
 	Status(ctx context.Context, name string, fl flags.StatusFlags) (release.Release, error)
 
+	// GetValues returns the values of a release revision, the values supplied by the user
+	// unless all values are requested in the flags
+	GetValues(ctx context.Context, name string, fl flags.GetValuesFlags) (Values, error)
+
 	Uninstall(ctx context.Context, name string, fl flags.UninstallFlags) (release.Release, error)
+
+	// History returns the revisions of a release, from the oldest to the latest
+	History(ctx context.Context, name string, fl flags.HistoryFlags) ([]release.Release, error)
+
+	// Rollback rolls a release back to a previous revision and returns the release
+	// created by the rollback
+	Rollback(ctx context.Context, name string, fl flags.RollbackFlags) (release.Release, error)
+
+	// AddRepo adds a chart repository with the given name and url to the albatross server,
+	// and returns the added repository
+	AddRepo(ctx context.Context, name string, url string, fl flags.AddRepoFlags) (repository.Repository, error)
+
+	// ListRepos returns the chart repositories configured on the albatross server
+	ListRepos(ctx context.Context) ([]repository.Repository, error)
+
+	// UpdateRepos fetches the latest chart indexes of the repositories
+	UpdateRepos(ctx context.Context, fl flags.UpdateRepoFlags) error
+
+	// RemoveRepo removes a chart repository from the albatross server
+	RemoveRepo(ctx context.Context, name string) error
+
+	// SearchCharts returns the charts in the configured repositories matching the query.
+	// If a version constraint is set in the flags, only matching versions are returned,
+	// from the highest to the lowest
+	SearchCharts(ctx context.Context, query string, fl flags.SearchFlags) ([]chart.Chart, error)
+
+	// ListChartVersions returns all versions of a chart, such as "stable/nginx",
+	// from the highest to the lowest
+	ListChartVersions(ctx context.Context, chartName string) ([]chart.Chart, error)
+
+	// Template renders the manifests of a chart without installing it, and returns them
+	// split into one document per kubernetes object
+	Template(ctx context.Context, name string, chart string, values Values, fl flags.TemplateFlags) ([]manifest.Document, error)
+
+	// Test runs the test hooks of a release and returns the result of every test pod.
+	// If any test does not succeed, the results are returned along with an *ErrTestFailed
+	Test(ctx context.Context, name string, fl flags.TestFlags) ([]release.TestResult, error)
+
+	// ForCluster returns a client that uses the kube context when the flags leave it empty
+	ForCluster(kubeContext string) Client
+
+	// InNamespace returns a client that uses the namespace when the flags leave it empty
+	InNamespace(namespace string) Client
+
+	// ValuesSchema returns the values.schema.json of a chart version, or nil if the chart has none
+	ValuesSchema(ctx context.Context, chartName string, version string) (*jsonschema.Schema, error)
+}
+
+// Scoper is implemented by clients that fill in the common flags with defaults, such as
+// the kube context and namespace they are bound to. Clients that wrap another client
+// implement it by calling Scope with the wrapped client
+type Scoper interface {
+	// Scope returns the common flags with the defaults of the client filled in, as they are sent
+	Scope(fl flags.CommonFlags) flags.CommonFlags
+}
+
+// Scope returns the common flags as the client sends them, if it is a Scoper, and the
+// flags as they are otherwise
+func Scope(client Client, fl flags.CommonFlags) flags.CommonFlags {
+	if scoper, ok := client.(Scoper); ok {
+		return scoper.Scope(fl)
+	}
+	return fl
 }
 
 // NewClient returns a new http client for the corresponding host
 // and config options.
 // In case of invalid host, it returns an error
 func NewClient(host string, opts ...config.Option) (Client, error) {
-	baseUrl, err := url.ParseRequestURI(host)
-	if err != nil {
-		return nil, err
-	}
-
 	cfg := config.DefaultConfig()
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	cfg.Host = host
+
+	return NewClientFromConfig(cfg)
+}
+
+// NewClientFromConfig returns a new http client for a complete config,
+// such as one built by config.Load.
+// In case of an invalid config, it returns an error
+func NewClientFromConfig(cfg *config.Config) (Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	// With multiple hosts, the http client picks the host for each request,
+	// so only the path and query are built here
+	baseUrl := &url.URL{}
+	if hosts := cfg.HostList(); len(hosts) == 1 {
+		var err error
+		if baseUrl, err = url.ParseRequestURI(hosts[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := httpclient.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	return &HttpClient{
 		baseUrl: baseUrl,
-		client:  httpclient.NewClient(cfg),
+		client:  client,
+		defaults: flags.CommonFlags{
+			KubeContext:   cfg.KubeContext,
+			Namespace:     cfg.Namespace,
+			KubeToken:     cfg.KubeToken,
+			KubeAPIServer: cfg.KubeAPIServer,
+		},
+		credentialsInHeaders: cfg.KubeCredentialsInHeaders,
+		schemaValidation:     cfg.ValidateValues,
 	}, nil
 }
@@ -3,6 +3,7 @@ package api
 import (
 	"testing"
 
+	"github.com/gojekfarm/albatross-client-go/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,3 +18,29 @@ func TestNewClientShouldFailForInvalidUrl(t *testing.T) {
 	_, err := NewClient(host)
 	assert.Error(t, err)
 }
+
+func TestNewClientFromConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Host = "http://localhost:8080"
+	_, err := NewClientFromConfig(cfg)
+	assert.NoError(t, err)
+}
+
+func TestNewClientFromConfigShouldFailForInvalidConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Host = "http://localhost:8080"
+	cfg.Timeout = -1
+	_, err := NewClientFromConfig(cfg)
+	assert.EqualError(t, err, "timeout cannot be negative")
+}
+
+func TestNewClientWithMultipleHosts(t *testing.T) {
+	client, err := NewClient("http://albatross-a:8080", config.WithHosts("http://albatross-b:8080"))
+	assert.NoError(t, err)
+	assert.Equal(t, "", client.(*HttpClient).baseUrl.String())
+}
+
+func TestNewClientShouldFailForInvalidAdditionalHost(t *testing.T) {
+	_, err := NewClient("http://albatross-a:8080", config.WithHosts("albatross-b"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotModified is returned by the read apis for a conditional request when the
+// response did not change since the entity tag of the validator
+var ErrNotModified = errors.New("not modified")
+
+// Validator makes the read requests of a context conditional. The entity tag of a
+// previous response is sent in If-None-Match, and the entity tag of the new response
+// is stored in ETag
+type Validator struct {
+	IfNoneMatch string
+	ETag        string
+}
+
+type validatorKey struct{}
+
+// WithValidator returns a context that makes the get requests sent with it conditional
+func WithValidator(ctx context.Context, validator *Validator) context.Context {
+	return context.WithValue(ctx, validatorKey{}, validator)
+}
+
+func validatorFrom(ctx context.Context) *Validator {
+	validator, _ := ctx.Value(validatorKey{}).(*Validator)
+	return validator
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpClientConditionalRequests(t *testing.T) {
+	fl := flags.StatusFlags{CommonFlags: flags.CommonFlags{KubeContext: "staging"}}
+
+	t.Run("stores the entity tag of the response", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 200, &statusResponse{Release: release.Release{Name: "api", Status: "deployed"}})
+		httpresponse.Header = http.Header{"Etag": []string{`"v2"`}}
+		apiclient.On("Send", mock.Anything, http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+		validator := &Validator{IfNoneMatch: `"v1"`}
+
+		rel, err := newTestHttpClient(apiclient).Status(WithValidator(context.Background(), validator), "api", fl)
+
+		require.NoError(t, err)
+		assert.Equal(t, "deployed", rel.Status)
+		assert.Equal(t, `"v2"`, validator.ETag)
+	})
+
+	t.Run("returns ErrNotModified when the response did not change", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse := &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{"Etag": []string{`"v1"`}}}
+		apiclient.On("Send", mock.Anything, http.MethodGet, nil).Return(httpresponse, nil, nil)
+		validator := &Validator{IfNoneMatch: `"v1"`}
+
+		_, err := newTestHttpClient(apiclient).Status(WithValidator(context.Background(), validator), "api", fl)
+
+		assert.True(t, errors.Is(err, ErrNotModified))
+		assert.Equal(t, `"v1"`, validator.ETag)
+	})
+}
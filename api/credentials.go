@@ -0,0 +1,29 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+)
+
+// ErrKubeCredentialsNotSent is returned by the apis without a request body, such as Status, List,
+// History, GetValues and Uninstall, when kube credentials are set but the client sends them in the
+// flags of request bodies. Configure the client with config.WithKubeCredentialsInHeaders to send
+// them with every request
+var ErrKubeCredentialsNotSent = errors.New("kube credentials cannot be sent without a request body, configure the client to send them in headers")
+
+// kubeCredentials are the kube credentials in the flags of request bodies, where albatross
+// servers expect them. The token is a plain string, as flags.Secret is redacted in json
+type kubeCredentials struct {
+	KubeToken     string `json:"kube_token,omitempty"`
+	KubeAPIServer string `json:"kube_apiserver,omitempty"`
+}
+
+// bodyCredentials returns the kube credentials to send in the flags of a request body,
+// which are empty if the client is configured to send them in headers
+func (c *HttpClient) bodyCredentials(fl flags.CommonFlags) kubeCredentials {
+	if c.credentialsInHeaders {
+		return kubeCredentials{}
+	}
+	return kubeCredentials{KubeToken: fl.KubeToken.Reveal(), KubeAPIServer: fl.KubeAPIServer}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gojekfarm/albatross-client-go/config"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/httpclient"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpClientSendsKubeCredentialsInBodyByDefault(t *testing.T) {
+	var header http.Header
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_ = json.NewEncoder(w).Encode(&installResponse{Status: "deployed"})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	require.NoError(t, err)
+
+	_, err = client.Install(context.Background(), "api", "stable/api", nil, flags.InstallFlags{
+		CommonFlags: flags.CommonFlags{KubeContext: "staging", KubeToken: "staging-token"},
+	})
+
+	require.NoError(t, err)
+	assert.Empty(t, header.Get(httpclient.HeaderKubeToken))
+	assert.Equal(t, map[string]interface{}{"dry_run": false, "version": "", "kube_token": "staging-token"}, body["Flags"])
+}
+
+func TestHttpClientSendsKubeCredentialsInHeadersWhenConfigured(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		_ = json.NewEncoder(w).Encode(&statusResponse{Release: release.Release{Name: "api", Status: "deployed"}})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL,
+		config.WithKubeContext("staging"),
+		config.WithKubeToken("staging-token"),
+		config.WithKubeAPIServer("https://kube.staging:6443"),
+		config.WithKubeCredentialsInHeaders(),
+	)
+	require.NoError(t, err)
+
+	_, err = client.Status(context.Background(), "api", flags.StatusFlags{})
+	require.NoError(t, err)
+	_, err = client.Install(context.Background(), "api", "stable/api", nil, flags.InstallFlags{})
+	require.NoError(t, err)
+
+	require.Len(t, requests, 2)
+	for i, r := range requests {
+		assert.Equal(t, "staging-token", r.Header.Get(httpclient.HeaderKubeToken))
+		assert.Equal(t, "https://kube.staging:6443", r.Header.Get(httpclient.HeaderKubeAPIServer))
+		assert.NotContains(t, r.URL.String(), "staging-token")
+		assert.NotContains(t, bodies[i], "staging-token")
+	}
+}
+
+func TestHttpClientFailsWithoutABodyToSendKubeCredentialsIn(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL,
+		config.WithKubeContext("staging"),
+		config.WithKubeToken("staging-token"),
+	)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = client.Status(ctx, "api", flags.StatusFlags{})
+	assert.Equal(t, ErrKubeCredentialsNotSent, err)
+	_, err = client.List(ctx, flags.ListFlags{})
+	assert.Equal(t, ErrKubeCredentialsNotSent, err)
+	_, err = client.History(ctx, "api", flags.HistoryFlags{})
+	assert.Equal(t, ErrKubeCredentialsNotSent, err)
+	_, err = client.GetValues(ctx, "api", flags.GetValuesFlags{})
+	assert.Equal(t, ErrKubeCredentialsNotSent, err)
+	_, err = client.Uninstall(ctx, "api", flags.UninstallFlags{})
+	assert.Equal(t, ErrKubeCredentialsNotSent, err)
+	assert.Zero(t, requests)
+
+	t.Run("When the flags are for another cluster without credentials", func(t *testing.T) {
+		fl := flags.StatusFlags{CommonFlags: flags.CommonFlags{KubeContext: "production"}}
+		_, err := client.Status(ctx, "api", fl)
+
+		assert.NotEqual(t, ErrKubeCredentialsNotSent, err)
+		assert.Equal(t, 1, requests)
+	})
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+)
+
+// getValuesResponse is the json schema to parse the get values api response
+type getValuesResponse struct {
+	Error  string `json:"error,omitempty"`
+	Values Values `json:"values,omitempty"`
+}
+
+// GetValues calls the get values api and returns the values of a release revision
+func (c *HttpClient) GetValues(ctx context.Context, name string, fl flags.GetValuesFlags) (Values, error) {
+	fl.CommonFlags = c.scope(fl.CommonFlags)
+	fl = fl.WithDefaults()
+	if err := flags.Join(flags.ValidReleaseName(name), fl.Valid()); err != nil {
+		return nil, err
+	}
+
+	reqPath := fmt.Sprintf("/clusters/%s/namespaces/%s/releases/%s/values", fl.KubeContext, fl.Namespace, name)
+	queryParams := url.Values{}
+	if err := encoder.Encode(fl, queryParams); err != nil {
+		return nil, err
+	}
+	httpResponse, data, err := c.request(ctx, fl.CommonFlags, reqPath, http.MethodGet, nil, queryParams.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if httpResponse.StatusCode == 404 {
+		return nil, fmt.Errorf("%w: %s", ErrReleaseNotFound, name)
+	}
+
+	var result getValuesResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("GetValues API returned an error: %s", result.Error)
+	}
+	if result.Values == nil {
+		return Values{}, nil
+	}
+	return result.Values, nil
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHttpClientGetValuesAPI(t *testing.T) {
+	t.Run("On success", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 200, &getValuesResponse{Values: Values{"replicas": 2}})
+		apiclient.On("Send", "http://localhost:8080/clusters/staging/namespaces/default/releases/api/values?all=true&revision=3", http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+		values, err := newTestHttpClient(apiclient).GetValues(context.Background(), "api", flags.GetValuesFlags{
+			Revision:    3,
+			All:         true,
+			CommonFlags: flags.CommonFlags{KubeContext: "staging"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, Values{"replicas": float64(2)}, values)
+	})
+
+	t.Run("When the release has no values", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 200, &getValuesResponse{})
+		apiclient.On("Send", mock.Anything, http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+		values, err := newTestHttpClient(apiclient).GetValues(context.Background(), "api", flags.GetValuesFlags{CommonFlags: flags.CommonFlags{KubeContext: "staging"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, Values{}, values)
+	})
+
+	t.Run("When the release is not found", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		apiclient.On("Send", mock.Anything, http.MethodGet, nil).Return(&http.Response{StatusCode: 404}, nil, nil)
+
+		_, err := newTestHttpClient(apiclient).GetValues(context.Background(), "api", flags.GetValuesFlags{CommonFlags: flags.CommonFlags{KubeContext: "staging"}})
+
+		assert.EqualError(t, err, "no release found: api")
+		assert.True(t, errors.Is(err, ErrReleaseNotFound))
+	})
+
+	t.Run("When the api returns an error", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 500, &getValuesResponse{Error: "cluster unreachable"})
+		apiclient.On("Send", mock.Anything, http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+		_, err := newTestHttpClient(apiclient).GetValues(context.Background(), "api", flags.GetValuesFlags{CommonFlags: flags.CommonFlags{KubeContext: "staging"}})
+
+		assert.EqualError(t, err, "GetValues API returned an error: cluster unreachable")
+	})
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+)
+
+// historyResponse is the json schema to parse the release history api response
+type historyResponse struct {
+	Error    string            `json:"error,omitempty"`
+	Releases []release.Release `json:"releases,omitempty"`
+}
+
+// rollbackRequest is the json schema for the rollback api
+type rollbackRequest struct {
+	Flags rollbackFlags
+}
+
+// rollbackFlags are the flags of the rollback api along with the kube credentials
+type rollbackFlags struct {
+	flags.RollbackFlags
+	kubeCredentials
+}
+
+// rollbackResponse is the json schema to parse the rollback api response
+type rollbackResponse struct {
+	Error   string          `json:"error,omitempty"`
+	Release release.Release `json:"release,omitempty"`
+}
+
+// History calls the release history api and returns the revisions of a release,
+// from the oldest to the latest
+func (c *HttpClient) History(ctx context.Context, name string, fl flags.HistoryFlags) ([]release.Release, error) {
+	fl.CommonFlags = c.scope(fl.CommonFlags)
+	fl = fl.WithDefaults()
+	if err := flags.Join(flags.ValidReleaseName(name), fl.Valid()); err != nil {
+		return nil, err
+	}
+
+	reqPath := fmt.Sprintf("/clusters/%s/namespaces/%s/releases/%s/history", fl.KubeContext, fl.Namespace, name)
+	queryParams := url.Values{}
+	if err := encoder.Encode(fl, queryParams); err != nil {
+		return nil, err
+	}
+	httpResponse, data, err := c.request(ctx, fl.CommonFlags, reqPath, http.MethodGet, nil, queryParams.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if httpResponse.StatusCode == 404 {
+		return nil, fmt.Errorf("%w: %s", ErrReleaseNotFound, name)
+	}
+
+	var result historyResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("History API returned an error: %s", result.Error)
+	}
+
+	sort.SliceStable(result.Releases, func(i, j int) bool {
+		return result.Releases[i].Version < result.Releases[j].Version
+	})
+	return result.Releases, nil
+}
+
+// Rollback calls the rollback api and returns the release created by the rollback
+func (c *HttpClient) Rollback(ctx context.Context, name string, fl flags.RollbackFlags) (release.Release, error) {
+	fl.CommonFlags = c.scope(fl.CommonFlags)
+	fl = fl.WithDefaults()
+	if err := flags.Join(flags.ValidReleaseName(name), fl.Valid()); err != nil {
+		return release.Release{}, err
+	}
+	reqBody, err := json.Marshal(&rollbackRequest{Flags: rollbackFlags{fl, c.bodyCredentials(fl.CommonFlags)}})
+	if err != nil {
+		return release.Release{}, err
+	}
+
+	reqPath := fmt.Sprintf("/clusters/%s/namespaces/%s/releases/%s/rollback", fl.KubeContext, fl.Namespace, name)
+	httpResponse, data, err := c.request(ctx, fl.CommonFlags, reqPath, http.MethodPost, bytes.NewBuffer(reqBody), "")
+	if err != nil {
+		return release.Release{}, err
+	}
+	if httpResponse.StatusCode == 404 {
+		return release.Release{}, fmt.Errorf("%w: %s", ErrReleaseNotFound, name)
+	}
+
+	var result rollbackResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return release.Release{}, err
+	}
+	if result.Error != "" {
+		return result.Release, fmt.Errorf("Rollback API returned an error: %s", result.Error)
+	}
+	return result.Release, nil
+}
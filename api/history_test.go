@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpClientHistoryAPI(t *testing.T) {
+	t.Run("On success", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 200, &historyResponse{Releases: []release.Release{
+			{Name: "api", Version: 3, Status: "deployed"},
+			{Name: "api", Version: 1, Status: "superseded"},
+			{Name: "api", Version: 2, Status: "superseded"},
+		}})
+		apiclient.On("Send", "http://localhost:8080/clusters/staging/namespaces/default/releases/api/history?max=10", http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+		history, err := newTestHttpClient(apiclient).History(context.Background(), "api", flags.HistoryFlags{
+			Max:         10,
+			CommonFlags: flags.CommonFlags{KubeContext: "staging"},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		assert.Equal(t, []int{1, 2, 3}, []int{history[0].Version, history[1].Version, history[2].Version})
+	})
+
+	t.Run("When the release is not found", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		apiclient.On("Send", mock.Anything, http.MethodGet, nil).Return(&http.Response{StatusCode: 404}, nil, nil)
+
+		_, err := newTestHttpClient(apiclient).History(context.Background(), "api", flags.HistoryFlags{CommonFlags: flags.CommonFlags{KubeContext: "staging"}})
+
+		assert.EqualError(t, err, "no release found: api")
+	})
+
+	t.Run("When the api returns an error", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 500, &historyResponse{Error: "cluster unreachable"})
+		apiclient.On("Send", mock.Anything, http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+		_, err := newTestHttpClient(apiclient).History(context.Background(), "api", flags.HistoryFlags{CommonFlags: flags.CommonFlags{KubeContext: "staging"}})
+
+		assert.EqualError(t, err, "History API returned an error: cluster unreachable")
+	})
+}
+
+func TestHttpClientRollbackAPI(t *testing.T) {
+	t.Run("On success", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 200, &rollbackResponse{Release: release.Release{Name: "api", Version: 5, Status: "deployed"}})
+		apiclient.On("Send", "http://localhost:8080/clusters/staging/namespaces/apps/releases/api/rollback", http.MethodPost, mock.Anything).Return(httpresponse, apiresponse, nil)
+
+		rel, err := newTestHttpClient(apiclient).Rollback(context.Background(), "api", flags.RollbackFlags{
+			Revision:    3,
+			Wait:        true,
+			CommonFlags: flags.CommonFlags{KubeContext: "staging", Namespace: "apps"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 5, rel.Version)
+		var body map[string]map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(sentBody(t, apiclient)), &body))
+		assert.Equal(t, map[string]interface{}{"revision": float64(3), "dry_run": false, "wait": true}, body["Flags"])
+	})
+
+	t.Run("When the api returns an error", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 500, &rollbackResponse{Error: "revision 3 not found"})
+		apiclient.On("Send", mock.Anything, http.MethodPost, mock.Anything).Return(httpresponse, apiresponse, nil)
+
+		_, err := newTestHttpClient(apiclient).Rollback(context.Background(), "api", flags.RollbackFlags{Revision: 3, CommonFlags: flags.CommonFlags{KubeContext: "staging"}})
+
+		assert.EqualError(t, err, "Rollback API returned an error: revision 3 not found")
+	})
+
+	t.Run("When the flags are invalid", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+
+		_, err := newTestHttpClient(apiclient).Rollback(context.Background(), "api", flags.RollbackFlags{Revision: -1, CommonFlags: flags.CommonFlags{KubeContext: "staging"}})
+
+		assert.EqualError(t, err, "revision cannot be negative")
+		apiclient.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
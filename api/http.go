@@ -13,16 +13,20 @@ import (
 	"strings"
 
 	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/httpclient"
 	"github.com/gojekfarm/albatross-client-go/release"
 	"github.com/gorilla/schema"
 )
 
 var encoder = schema.NewEncoder()
 
+// ErrReleaseNotFound is wrapped by the errors of the release apis when the release does not exist
+var ErrReleaseNotFound = errors.New("no release found")
+
 // APIClient defines the contract for the http client implementation to send requests to
 // the albatross api server
 type APIClient interface {
-	Send(url string, method string, body io.Reader) (*http.Response, []byte, error)
+	Send(ctx context.Context, url string, method string, body io.Reader) (*http.Response, []byte, error)
 }
 
 // HttpClient is responsible to sending api requests and parsing their responses
@@ -31,16 +35,31 @@ type APIClient interface {
 type HttpClient struct {
 	baseUrl *url.URL
 	client  APIClient
+
+	// defaults fill the common flags left empty by the caller
+	defaults flags.CommonFlags
+
+	// credentialsInHeaders sends the kube credentials in headers instead of the flags of request bodies
+	credentialsInHeaders bool
+
+	// schemaValidation validates values against the schema of the chart before install and upgrade
+	schemaValidation bool
 }
 
 // installRequest is the json schema for the install api
 type installRequest struct {
 	Chart  string
 	Values Values
-	Flags  flags.InstallFlags
+	Flags  installFlags
 	Name   string
 }
 
+// installFlags are the flags of the install api along with the kube credentials
+type installFlags struct {
+	flags.InstallFlags
+	kubeCredentials
+}
+
 // installResponse is the json schema to parse the install api response
 type installResponse struct {
 	Error  string `json:"error,omitempty"`
@@ -52,7 +71,13 @@ type installResponse struct {
 type upgradeRequest struct {
 	Chart  string
 	Values Values
-	Flags  flags.UpgradeFlags
+	Flags  upgradeFlags
+}
+
+// upgradeFlags are the flags of the upgrade api along with the kube credentials
+type upgradeFlags struct {
+	flags.UpgradeFlags
+	kubeCredentials
 }
 
 // upgradeResponse is the json schema to parse the upgrade api response
@@ -80,16 +105,56 @@ type unintstallResponse struct {
 	Release release.Release `json:"release,omitempty"`
 }
 
-// request is a helper function to append the path to baseUrl and send the request to the APIClient
-func (c *HttpClient) request(ctx context.Context, reqPath string, method string, body io.Reader, queryString string) (*http.Response, []byte, error) {
+// request is a helper function to append the path to baseUrl and send the request to the APIClient.
+// reqPath is escaped, segments that are not validated by the api, such as repository names,
+// are escaped with url.PathEscape by the caller.
+// The kube context is passed along with ctx so that rate limits can be kept per kube context,
+// and so are the kube credentials, which are sent in headers if the client is configured to.
+// Otherwise they are sent in the flags of the body, and requests without a body fail with
+// ErrKubeCredentialsNotSent when credentials are set.
+// Get requests are conditional if ctx carries a Validator, see WithValidator
+func (c *HttpClient) request(ctx context.Context, fl flags.CommonFlags, reqPath string, method string, body io.Reader, queryString string) (*http.Response, []byte, error) {
+	if body == nil && !c.credentialsInHeaders && (fl.KubeToken != "" || fl.KubeAPIServer != "") {
+		return nil, nil, ErrKubeCredentialsNotSent
+	}
+
 	u := *c.baseUrl
-	u.Path = path.Join(strings.TrimRight(u.Path, "/"), reqPath)
+	u.RawPath = path.Join(strings.TrimRight(u.EscapedPath(), "/"), reqPath)
+	unescaped, err := url.PathUnescape(u.RawPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	u.Path = unescaped
 	u.RawQuery = queryString
-	return c.client.Send(u.String(), method, body)
+	ctx = httpclient.WithKubeContext(ctx, fl.KubeContext)
+	if c.credentialsInHeaders {
+		ctx = httpclient.WithKubeCredentials(ctx, fl.KubeToken.Reveal(), fl.KubeAPIServer)
+	}
+
+	validator := validatorFrom(ctx)
+	if validator == nil || method != http.MethodGet {
+		return c.client.Send(ctx, u.String(), method, body)
+	}
+	httpResponse, data, err := c.client.Send(httpclient.WithIfNoneMatch(ctx, validator.IfNoneMatch), u.String(), method, body)
+	if err != nil || httpResponse == nil {
+		return httpResponse, data, err
+	}
+	validator.ETag = httpResponse.Header.Get("ETag")
+	if httpResponse.StatusCode == http.StatusNotModified {
+		return httpResponse, data, ErrNotModified
+	}
+	return httpResponse, data, nil
 }
 
 // List sends the list api request to the APIClient and returns a list of releases if successfull.
 func (c *HttpClient) List(ctx context.Context, fl flags.ListFlags) ([]release.Release, error) {
+	namespace := fl.Namespace
+	fl.CommonFlags = c.scope(fl.CommonFlags)
+	if fl.AllNamespaces {
+		// The default namespace of the client does not apply when listing all namespaces
+		fl.Namespace = namespace
+	}
+	fl = fl.WithDefaults()
 	if err := fl.Valid(); err != nil {
 		return nil, err
 	}
@@ -105,7 +170,7 @@ func (c *HttpClient) List(ctx context.Context, fl flags.ListFlags) ([]release.Re
 	if err != nil {
 		return nil, err
 	}
-	httpResponse, data, err := c.request(ctx, reqPath, http.MethodGet, nil, queryParams.Encode())
+	httpResponse, data, err := c.request(ctx, fl.CommonFlags, reqPath, http.MethodGet, nil, queryParams.Encode())
 	if err != nil {
 		return nil, err
 	}
@@ -127,11 +192,9 @@ func (c *HttpClient) List(ctx context.Context, fl flags.ListFlags) ([]release.Re
 }
 
 func (c *HttpClient) Status(ctx context.Context, name string, fl flags.StatusFlags) (release.Release, error) {
-	if name == "" {
-		return release.Release{}, errors.New("name cannot be empty")
-	}
-
-	if err := fl.Valid(); err != nil {
+	fl.CommonFlags = c.scope(fl.CommonFlags)
+	fl = fl.WithDefaults()
+	if err := flags.Join(flags.ValidReleaseName(name), fl.Valid()); err != nil {
 		return release.Release{}, err
 	}
 
@@ -142,12 +205,12 @@ func (c *HttpClient) Status(ctx context.Context, name string, fl flags.StatusFla
 	if err != nil {
 		return release.Release{}, err
 	}
-	httpResponse, data, err := c.request(ctx, reqPath, http.MethodGet, nil, queryParams.Encode())
+	httpResponse, data, err := c.request(ctx, fl.CommonFlags, reqPath, http.MethodGet, nil, queryParams.Encode())
 	if err != nil {
 		return release.Release{}, err
 	}
 	if httpResponse.StatusCode == 404 {
-		return release.Release{}, fmt.Errorf("no release found: %s", name)
+		return release.Release{}, fmt.Errorf("%w: %s", ErrReleaseNotFound, name)
 	}
 
 	var result statusResponse
@@ -164,98 +227,116 @@ func (c *HttpClient) Status(ctx context.Context, name string, fl flags.StatusFla
 
 // Install calls the install api and returns the status
 // TODO: Make install api return an installed release rather than just the status
-func (c *HttpClient) Install(ctx context.Context, name string, chart string, values Values, fl flags.InstallFlags) (string, error) {
-	if err := fl.Valid(); err != nil {
-		return "", err
+func (c *HttpClient) Install(ctx context.Context, name string, chart string, values Values, fl flags.InstallFlags) (Result, error) {
+	fl.CommonFlags = c.scope(fl.CommonFlags)
+	fl = fl.WithDefaults()
+	if err := flags.Join(flags.ValidReleaseName(name), fl.Valid()); err != nil {
+		return Result{}, err
 	}
 
-	if name == "" {
-		return "", errors.New("name cannot be empty")
+	version, err := c.resolveVersion(ctx, chart, fl.Version)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if err := c.validateValues(ctx, chart, version, values, fl.ValuesSchemaFile); err != nil {
+		return Result{}, err
 	}
+	fl.Version = version
+
 	reqBody, err := json.Marshal(&installRequest{
 		Chart:  chart,
 		Values: values,
-		Flags:  fl,
+		Flags:  installFlags{fl, c.bodyCredentials(fl.CommonFlags)},
 		Name:   name,
 	})
 	if err != nil {
-		return "", err
+		return Result{}, err
 	}
 	reqPath := fmt.Sprintf("/clusters/%s/namespaces/%s/releases", fl.KubeContext, fl.Namespace)
 
-	_, data, err := c.request(ctx, reqPath, http.MethodPost, bytes.NewBuffer(reqBody), "")
+	_, data, err := c.request(ctx, fl.CommonFlags, reqPath, http.MethodPost, bytes.NewBuffer(reqBody), "")
 	if err != nil {
-		return "", err
+		return Result{}, err
 	}
 
 	var result installResponse
 	if err := json.Unmarshal(data, &result); err != nil {
-		return "", err
+		return Result{}, err
 	}
 
 	if result.Error != "" {
-		return "", fmt.Errorf("Install API returned an error: %s", result.Error)
+		return Result{}, fmt.Errorf("Install API returned an error: %s", result.Error)
 
 	}
 
-	return result.Status, nil
+	return Result{Status: result.Status, Version: version}, nil
 }
 
 // Upgrade calls the upgrade api and returns the status
-func (c *HttpClient) Upgrade(ctx context.Context, name string, chart string, values Values, fl flags.UpgradeFlags) (string, error) {
-	if err := fl.Valid(); err != nil {
-		return "", err
+func (c *HttpClient) Upgrade(ctx context.Context, name string, chart string, values Values, fl flags.UpgradeFlags) (Result, error) {
+	fl.CommonFlags = c.scope(fl.CommonFlags)
+	fl = fl.WithDefaults()
+	if err := flags.Join(flags.ValidReleaseName(name), fl.Valid()); err != nil {
+		return Result{}, err
+	}
+
+	version, err := c.resolveVersion(ctx, chart, fl.Version)
+	if err != nil {
+		return Result{}, err
 	}
-	if name == "" {
-		return "", errors.New("name cannot be empty")
+
+	if err := c.validateValues(ctx, chart, version, values, fl.ValuesSchemaFile); err != nil {
+		return Result{}, err
 	}
+	fl.Version = version
+
 	reqBody, err := json.Marshal(&upgradeRequest{
 		Chart:  chart,
 		Values: values,
-		Flags:  fl,
+		Flags:  upgradeFlags{fl, c.bodyCredentials(fl.CommonFlags)},
 	})
 	if err != nil {
-		return "", err
+		return Result{}, err
 	}
 	reqPath := fmt.Sprintf("/clusters/%s/namespaces/%s/releases/%s", fl.KubeContext, fl.Namespace, name)
 
-	_, data, err := c.request(ctx, reqPath, http.MethodPut, bytes.NewBuffer(reqBody), "")
+	_, data, err := c.request(ctx, fl.CommonFlags, reqPath, http.MethodPut, bytes.NewBuffer(reqBody), "")
 	if err != nil {
-		return "", err
+		return Result{}, err
 	}
 
 	var result upgradeResponse
 	if err := json.Unmarshal(data, &result); err != nil {
-		return "", err
+		return Result{}, err
 	}
 
 	if result.Error != "" {
-		return "", fmt.Errorf("Upgrade API returned an error: %s", result.Error)
+		return Result{}, fmt.Errorf("Upgrade API returned an error: %s", result.Error)
 
 	}
 
-	return result.Status, nil
+	return Result{Status: result.Status, Version: version}, nil
 }
 
 func (c *HttpClient) Uninstall(ctx context.Context, name string, fl flags.UninstallFlags) (release.Release, error) {
-	if err := fl.Valid(); err != nil {
+	fl.CommonFlags = c.scope(fl.CommonFlags)
+	fl = fl.WithDefaults()
+	if err := flags.Join(flags.ValidReleaseName(name), fl.Valid()); err != nil {
 		return release.Release{}, err
 	}
-	if name == "" {
-		return release.Release{}, errors.New("name cannot be empty")
-	}
 	reqPath := fmt.Sprintf("/clusters/%s/namespaces/%s/releases/%s", fl.KubeContext, fl.Namespace, name)
 	queryParams := url.Values{}
 	err := encoder.Encode(fl, queryParams)
 	if err != nil {
 		return release.Release{}, err
 	}
-	httpResponse, data, err := c.request(ctx, reqPath, http.MethodDelete, nil, queryParams.Encode())
+	httpResponse, data, err := c.request(ctx, fl.CommonFlags, reqPath, http.MethodDelete, nil, queryParams.Encode())
 	if err != nil {
 		return release.Release{}, err
 	}
 	if httpResponse.StatusCode == 404 {
-		return release.Release{}, fmt.Errorf("no release found: %s", name)
+		return release.Release{}, fmt.Errorf("%w: %s", ErrReleaseNotFound, name)
 	}
 
 	var result unintstallResponse
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -22,7 +23,7 @@ type mockAPIClient struct {
 	mock.Mock
 }
 
-func (m *mockAPIClient) Send(url string, method string, body io.Reader) (*http.Response, []byte, error) {
+func (m *mockAPIClient) Send(ctx context.Context, url string, method string, body io.Reader) (*http.Response, []byte, error) {
 	args := m.Called(url, method, body)
 	if args.Get(1) == nil {
 		return args.Get(0).(*http.Response), nil, args.Error(2)
@@ -66,14 +67,14 @@ func TestHttpClientInstallAPIOnSuccess(t *testing.T) {
 	expectedReq, err := json.Marshal(&installRequest{
 		Chart:  "testchart",
 		Values: values,
-		Flags:  fl,
+		Flags:  installFlags{InstallFlags: fl},
 		Name:   releaseName,
 	})
 	assert.NoError(t, err)
 	apiclient.On("Send", expectedURL, http.MethodPost, bytes.NewBuffer(expectedReq)).Return(httpresponse, apiresponse, nil).Once()
 	result, err := httpclient.Install(context.Background(), releaseName, "testchart", values, fl)
 	assert.NoError(t, err)
-	assert.Equal(t, result, "deployed")
+	assert.Equal(t, result.Status, "deployed")
 	apiclient.AssertExpectations(t)
 }
 
@@ -111,7 +112,7 @@ func TestHttpClientInstallAPIOnFailure(t *testing.T) {
 	jsonRequest, err := json.Marshal(&installRequest{
 		Chart:  "",
 		Values: values,
-		Flags:  fl,
+		Flags:  installFlags{InstallFlags: fl},
 		Name:   "testrelease",
 	})
 	require.NoError(t, err)
@@ -150,6 +151,24 @@ func TestHttpClientInstallAPIReturnsErrorWhenNameIsEmptyString(t *testing.T) {
 	apiclient.AssertExpectations(t)
 }
 
+func TestHttpClientInstallAPIReturnsAllValidationErrors(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	fl := flags.InstallFlags{
+		Timeout:     -1,
+		CommonFlags: flags.CommonFlags{Namespace: "apps"},
+	}
+
+	_, err := newTestHttpClient(apiclient).Install(context.Background(), "Api_v2", "stable/api", nil, fl)
+
+	var errs flags.Errors
+	require.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 3)
+	assert.Contains(t, errs[0].Error(), `invalid release name "Api_v2"`)
+	assert.EqualError(t, errs[1], "kube context is a required parameter")
+	assert.EqualError(t, errs[2], "timeout cannot be negative")
+	apiclient.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestHttpClientUpgradeAPIOnSuccess(t *testing.T) {
 	apiclient := new(mockAPIClient)
 	apiresponse, err := json.Marshal(&upgradeResponse{
@@ -185,7 +204,7 @@ func TestHttpClientUpgradeAPIOnSuccess(t *testing.T) {
 	req, err := json.Marshal(&upgradeRequest{
 		Chart:  "testchart",
 		Values: values,
-		Flags:  fl,
+		Flags:  upgradeFlags{UpgradeFlags: fl},
 	})
 	assert.NoError(t, err)
 	expectedURL := fmt.Sprintf("http://localhost:8080/clusters/%s/namespaces/%s/releases/%s", cluster, namespace, releaseName)
@@ -194,7 +213,7 @@ func TestHttpClientUpgradeAPIOnSuccess(t *testing.T) {
 	result, err := httpclient.Upgrade(context.Background(), releaseName, "testchart", values, fl)
 
 	assert.NoError(t, err)
-	assert.Equal(t, result, "deployed")
+	assert.Equal(t, result.Status, "deployed")
 }
 
 func TestHttpClientUpgradeAPIOnFailure(t *testing.T) {
@@ -230,7 +249,7 @@ func TestHttpClientUpgradeAPIOnFailure(t *testing.T) {
 	req, err := json.Marshal(&upgradeRequest{
 		Chart:  "testchart",
 		Values: values,
-		Flags:  fl,
+		Flags:  upgradeFlags{UpgradeFlags: fl},
 	})
 	assert.NoError(t, err)
 	expectedURL := fmt.Sprintf("http://localhost:8080/clusters/%s/namespaces/%s/releases/%s", cluster, namespace, releaseName)
@@ -242,6 +261,132 @@ func TestHttpClientUpgradeAPIOnFailure(t *testing.T) {
 	assert.EqualError(t, err, "Upgrade API returned an error: Invalid Request")
 }
 
+func TestHttpClientInstallAPISendsHelmFlags(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 200, &installResponse{Status: "deployed"})
+	fl := flags.InstallFlags{
+		Wait:            true,
+		Atomic:          true,
+		Timeout:         600,
+		CreateNamespace: true,
+		Description:     "first install",
+		DisableHooks:    true,
+		SkipCRDs:        true,
+		CommonFlags:     flags.CommonFlags{KubeContext: "staging", Namespace: "apps"},
+	}
+	expectedReq := mustMarshal(t, &installRequest{Chart: "stable/nginx", Flags: installFlags{InstallFlags: fl}, Name: "nginx"})
+	apiclient.On("Send", "http://localhost:8080/clusters/staging/namespaces/apps/releases", http.MethodPost, bytes.NewBuffer(expectedReq)).Return(httpresponse, apiresponse, nil)
+
+	_, err := newTestHttpClient(apiclient).Install(context.Background(), "nginx", "stable/nginx", nil, fl)
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"Chart": "stable/nginx",
+		"Values": null,
+		"Name": "nginx",
+		"Flags": {
+			"dry_run": false,
+			"version": "",
+			"wait": true,
+			"atomic": true,
+			"timeout": 600,
+			"create_namespace": true,
+			"description": "first install",
+			"disable_hooks": true,
+			"skip_crds": true
+		}
+	}`, string(expectedReq))
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientUpgradeAPISendsHelmFlags(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 200, &upgradeResponse{Status: "deployed"})
+	fl := flags.UpgradeFlags{
+		Install:         true,
+		Wait:            true,
+		Atomic:          true,
+		Timeout:         300,
+		Force:           true,
+		ReuseValues:     true,
+		CreateNamespace: true,
+		Description:     "bump",
+		DisableHooks:    true,
+		SkipCRDs:        true,
+		MaxHistory:      10,
+		CleanupOnFail:   true,
+		CommonFlags:     flags.CommonFlags{KubeContext: "staging", Namespace: "apps"},
+	}
+	expectedReq := mustMarshal(t, &upgradeRequest{Chart: "stable/nginx", Flags: upgradeFlags{UpgradeFlags: fl}})
+	apiclient.On("Send", "http://localhost:8080/clusters/staging/namespaces/apps/releases/nginx", http.MethodPut, bytes.NewBuffer(expectedReq)).Return(httpresponse, apiresponse, nil)
+
+	_, err := newTestHttpClient(apiclient).Upgrade(context.Background(), "nginx", "stable/nginx", nil, fl)
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"Chart": "stable/nginx",
+		"Values": null,
+		"Flags": {
+			"dry_run": false,
+			"version": "",
+			"install": true,
+			"wait": true,
+			"atomic": true,
+			"timeout": 300,
+			"force": true,
+			"reuse_values": true,
+			"create_namespace": true,
+			"description": "bump",
+			"disable_hooks": true,
+			"skip_crds": true,
+			"max_history": 10,
+			"cleanup_on_fail": true
+		}
+	}`, string(expectedReq))
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientUpgradeAPIReturnsErrorOnConflictingFlags(t *testing.T) {
+	testCases := []struct {
+		name  string
+		flags flags.UpgradeFlags
+		err   string
+	}{
+		{
+			name:  "reset and reuse values",
+			flags: flags.UpgradeFlags{ResetValues: true, ReuseValues: true},
+			err:   "reset values and reuse values cannot be used together",
+		},
+		{
+			name:  "create namespace without install",
+			flags: flags.UpgradeFlags{CreateNamespace: true},
+			err:   "create namespace can only be used together with install",
+		},
+		{
+			name:  "negative timeout",
+			flags: flags.UpgradeFlags{Timeout: -1},
+			err:   "timeout cannot be negative",
+		},
+		{
+			name:  "negative max history",
+			flags: flags.UpgradeFlags{MaxHistory: -1},
+			err:   "max history cannot be negative",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiclient := new(mockAPIClient)
+			tc.flags.KubeContext = "staging"
+
+			_, err := newTestHttpClient(apiclient).Upgrade(context.Background(), "nginx", "stable/nginx", nil, tc.flags)
+
+			assert.EqualError(t, err, tc.err)
+			apiclient.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
 func TestHttpClientListAPIOnSuccess(t *testing.T) {
 	apiclient := new(mockAPIClient)
 	apiresponse, err := json.Marshal(&listResponse{
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+)
+
+// DefaultPageSize is the number of releases a ListIterator fetches per call
+// when the list flags do not set a limit
+const DefaultPageSize = 100
+
+// ListIterator walks through the releases of the list api page by page.
+// It starts at the offset of the flags and uses their limit as the page size
+//
+//	it := api.NewListIterator(client, fl)
+//	for it.Next(ctx) {
+//		rel := it.Release()
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type ListIterator struct {
+	client Client
+	flags  flags.ListFlags
+
+	page    []release.Release
+	current release.Release
+	done    bool
+	err     error
+
+	// first is the first release of the last page, to detect servers that ignore the offset
+	first *release.Release
+}
+
+// NewListIterator returns an iterator over all releases matching the flags
+func NewListIterator(client Client, fl flags.ListFlags) *ListIterator {
+	if fl.Limit == 0 {
+		fl.Limit = DefaultPageSize
+	}
+	return &ListIterator{client: client, flags: fl}
+}
+
+// Next advances to the next release, fetching the next page when the current one
+// is exhausted. It returns false when there are no more releases or a call failed.
+// The releases end at a short or empty page, or at a page that starts with the same
+// release as the previous one, which servers that ignore the offset return
+func (it *ListIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+
+	if len(it.page) == 0 {
+		if it.done {
+			return false
+		}
+		page, err := it.client.List(ctx, it.flags)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if len(page) == 0 || (it.first != nil && sameRelease(page[0], *it.first)) {
+			// A page that repeats the previous one means the server ignores the offset
+			it.done = true
+			return false
+		}
+		// A short page is the last one. A longer page means the server does
+		// not support pagination and returned all releases at once
+		it.done = len(page) != it.flags.Limit
+		it.flags.Offset += len(page)
+		it.page = page
+		it.first = &page[0]
+	}
+
+	it.current = it.page[0]
+	it.page = it.page[1:]
+	return true
+}
+
+func sameRelease(a release.Release, b release.Release) bool {
+	return a.Name == b.Name && a.Namespace == b.Namespace && a.Version == b.Version
+}
+
+// Release returns the release Next advanced to
+func (it *ListIterator) Release() release.Release {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *ListIterator) Err() error {
+	return it.err
+}
+
+// ListAll fetches all pages of releases matching the flags
+func ListAll(ctx context.Context, client Client, fl flags.ListFlags) ([]release.Release, error) {
+	releases := []release.Release{}
+	it := NewListIterator(client, fl)
+	for it.Next(ctx) {
+		releases = append(releases, it.Release())
+	}
+	return releases, it.Err()
+}
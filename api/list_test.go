@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHttpClientListAPIEncodesFilterSortAndPagination(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 200, &listResponse{
+		Releases: []release.Release{{Name: "api-v2"}},
+	})
+	expectedURL := "http://localhost:8080/clusters/staging/namespaces/apps/releases?" +
+		"deployed=true&filter=%5Eapi-&limit=20&offset=40&reverse=true&selector=team%3Dpayments&sort_by=date"
+	apiclient.On("Send", expectedURL, http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+	releases, err := newTestHttpClient(apiclient).List(context.Background(), flags.ListFlags{
+		Deployed:    true,
+		Filter:      "^api-",
+		Selector:    "team=payments",
+		SortBy:      flags.SortByDate,
+		Reverse:     true,
+		Limit:       20,
+		Offset:      40,
+		CommonFlags: flags.CommonFlags{KubeContext: "staging", Namespace: "apps"},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, releases, 1)
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientListAPIReturnsErrorOnInvalidFlags(t *testing.T) {
+	testCases := []struct {
+		name  string
+		flags flags.ListFlags
+		err   string
+	}{
+		{
+			name:  "invalid filter",
+			flags: flags.ListFlags{Filter: "api-("},
+			err:   "invalid filter \"api-(\": error parsing regexp: missing closing ): `api-(`",
+		},
+		{
+			name:  "invalid sort",
+			flags: flags.ListFlags{SortBy: "size"},
+			err:   "invalid sort \"size\", must be \"name\" or \"date\"",
+		},
+		{
+			name:  "negative limit",
+			flags: flags.ListFlags{Limit: -1},
+			err:   "limit cannot be negative",
+		},
+		{
+			name:  "negative offset",
+			flags: flags.ListFlags{Offset: -1},
+			err:   "offset cannot be negative",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiclient := new(mockAPIClient)
+			tc.flags.KubeContext = "staging"
+
+			_, err := newTestHttpClient(apiclient).List(context.Background(), tc.flags)
+
+			assert.EqualError(t, err, tc.err)
+			apiclient.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestListAllFetchesEveryPage(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	pages := map[string][]release.Release{
+		"limit=2&offset=10": {{Name: "a"}, {Name: "b"}},
+		"limit=2&offset=12": {{Name: "c"}, {Name: "d"}},
+		"limit=2&offset=14": {{Name: "e"}},
+	}
+	for query, page := range pages {
+		httpresponse, apiresponse := jsonResponse(t, 200, &listResponse{Releases: page})
+		apiclient.On("Send", "http://localhost:8080/clusters/staging/namespaces/apps/releases?"+query, http.MethodGet, nil).
+			Return(httpresponse, apiresponse, nil).Once()
+	}
+
+	releases, err := ListAll(context.Background(), newTestHttpClient(apiclient), flags.ListFlags{
+		Limit:       2,
+		Offset:      10,
+		CommonFlags: flags.CommonFlags{KubeContext: "staging", Namespace: "apps"},
+	})
+
+	assert.NoError(t, err)
+	var names []string
+	for _, rel := range releases {
+		names = append(names, rel.Name)
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, names)
+	apiclient.AssertExpectations(t)
+}
+
+func TestListIteratorStopsOnEmptyPage(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 200, &listResponse{Releases: []release.Release{{Name: "a"}, {Name: "b"}}})
+	apiclient.On("Send", "http://localhost:8080/clusters/staging/releases?limit=2", http.MethodGet, nil).Return(httpresponse, apiresponse, nil).Once()
+	apiclient.On("Send", "http://localhost:8080/clusters/staging/releases?limit=2&offset=2", http.MethodGet, nil).Return(&http.Response{StatusCode: 204}, nil, nil).Once()
+
+	it := NewListIterator(newTestHttpClient(apiclient), flags.ListFlags{
+		AllNamespaces: true,
+		Limit:         2,
+		CommonFlags:   flags.CommonFlags{KubeContext: "staging"},
+	})
+	count := 0
+	for it.Next(context.Background()) {
+		count++
+	}
+
+	assert.NoError(t, it.Err())
+	assert.Equal(t, 2, count)
+	assert.False(t, it.Next(context.Background()))
+	apiclient.AssertExpectations(t)
+}
+
+func TestListIteratorStopsWhenTheServerIgnoresTheOffset(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	page := []release.Release{{Name: "a", Namespace: "apps", Version: 1}, {Name: "b", Namespace: "apps", Version: 3}}
+	for _, query := range []string{"limit=2", "limit=2&offset=2"} {
+		httpresponse, apiresponse := jsonResponse(t, 200, &listResponse{Releases: page})
+		apiclient.On("Send", "http://localhost:8080/clusters/staging/namespaces/apps/releases?"+query, http.MethodGet, nil).
+			Return(httpresponse, apiresponse, nil).Once()
+	}
+
+	releases, err := ListAll(context.Background(), newTestHttpClient(apiclient), flags.ListFlags{
+		Limit:       2,
+		CommonFlags: flags.CommonFlags{KubeContext: "staging", Namespace: "apps"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, page, releases)
+	apiclient.AssertExpectations(t)
+}
+
+func TestListIteratorReturnsError(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 500, &listResponse{Error: "cluster unreachable"})
+	apiclient.On("Send", mock.Anything, http.MethodGet, nil).Return(httpresponse, apiresponse, nil).Once()
+
+	releases, err := ListAll(context.Background(), newTestHttpClient(apiclient), flags.ListFlags{
+		CommonFlags: flags.CommonFlags{KubeContext: "staging"},
+	})
+
+	assert.EqualError(t, err, "List API returned an error: cluster unreachable")
+	assert.Empty(t, releases)
+	apiclient.AssertExpectations(t)
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/repository"
+)
+
+// addRepoRequest is the json schema for the add repository api
+type addRepoRequest struct {
+	URL string `json:"url"`
+	flags.AddRepoFlags
+	Password string `json:"password,omitempty"`
+}
+
+// addRepoResponse is the json schema to parse the add repository api response
+type addRepoResponse struct {
+	Error      string                `json:"error,omitempty"`
+	Repository repository.Repository `json:"repository,omitempty"`
+}
+
+// listReposResponse is the json schema to parse the list repositories api response
+type listReposResponse struct {
+	Error        string                  `json:"error,omitempty"`
+	Repositories []repository.Repository `json:"repositories,omitempty"`
+}
+
+// repoResponse is the json schema to parse the update and remove repository api responses
+type repoResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// repoPath returns the path of the repository apis for a repository
+func repoPath(name string) string {
+	return "/repositories/" + url.PathEscape(name)
+}
+
+// AddRepo calls the add repository api and returns the added repository
+func (c *HttpClient) AddRepo(ctx context.Context, name string, url string, fl flags.AddRepoFlags) (repository.Repository, error) {
+	if err := fl.Valid(); err != nil {
+		return repository.Repository{}, err
+	}
+	if err := flags.ValidRepoName(name); err != nil {
+		return repository.Repository{}, err
+	}
+	if url == "" {
+		return repository.Repository{}, errors.New("url cannot be empty")
+	}
+	reqBody, err := json.Marshal(&addRepoRequest{
+		URL:          url,
+		AddRepoFlags: fl,
+		Password:     fl.Password.Reveal(),
+	})
+	if err != nil {
+		return repository.Repository{}, err
+	}
+	reqPath := repoPath(name)
+
+	_, data, err := c.request(ctx, flags.CommonFlags{}, reqPath, http.MethodPut, bytes.NewBuffer(reqBody), "")
+	if err != nil {
+		return repository.Repository{}, err
+	}
+
+	var result addRepoResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return repository.Repository{}, err
+	}
+
+	if result.Error != "" {
+		return repository.Repository{}, fmt.Errorf("AddRepo API returned an error: %s", result.Error)
+	}
+
+	return result.Repository, nil
+}
+
+// ListRepos calls the list repositories api and returns the configured repositories
+func (c *HttpClient) ListRepos(ctx context.Context) ([]repository.Repository, error) {
+	httpResponse, data, err := c.request(ctx, flags.CommonFlags{}, "/repositories", http.MethodGet, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if httpResponse.StatusCode == 204 {
+		return []repository.Repository{}, nil
+	}
+
+	var result listReposResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Error != "" {
+		return nil, fmt.Errorf("ListRepos API returned an error: %s", result.Error)
+	}
+
+	return result.Repositories, nil
+}
+
+// UpdateRepos calls the update repositories api
+func (c *HttpClient) UpdateRepos(ctx context.Context, fl flags.UpdateRepoFlags) error {
+	for _, name := range fl.Names {
+		if err := flags.ValidRepoName(name); err != nil {
+			return err
+		}
+	}
+	reqBody, err := json.Marshal(&fl)
+	if err != nil {
+		return err
+	}
+
+	_, data, err := c.request(ctx, flags.CommonFlags{}, "/repositories/update", http.MethodPost, bytes.NewBuffer(reqBody), "")
+	if err != nil {
+		return err
+	}
+
+	var result repoResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+
+	if result.Error != "" {
+		return fmt.Errorf("UpdateRepos API returned an error: %s", result.Error)
+	}
+
+	return nil
+}
+
+// RemoveRepo calls the remove repository api
+func (c *HttpClient) RemoveRepo(ctx context.Context, name string) error {
+	if err := flags.ValidRepoName(name); err != nil {
+		return err
+	}
+	reqPath := repoPath(name)
+
+	httpResponse, data, err := c.request(ctx, flags.CommonFlags{}, reqPath, http.MethodDelete, nil, "")
+	if err != nil {
+		return err
+	}
+	if httpResponse.StatusCode == 404 {
+		return fmt.Errorf("no repository found: %s", name)
+	}
+
+	var result repoResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+
+	if result.Error != "" {
+		return fmt.Errorf("RemoveRepo API returned an error: %s", result.Error)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHttpClient(apiclient *mockAPIClient) *HttpClient {
+	baseURL, _ := url.ParseRequestURI("http://localhost:8080")
+	return &HttpClient{
+		baseUrl: baseURL,
+		client:  apiclient,
+	}
+}
+
+func jsonResponse(t *testing.T, statusCode int, body interface{}) (*http.Response, []byte) {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	return &http.Response{
+		StatusCode: statusCode,
+		Body:       ioutil.NopCloser(bytes.NewReader(data)),
+	}, data
+}
+
+func TestHttpClientAddRepoAPIOnSuccess(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 200, &addRepoResponse{
+		Repository: repository.Repository{Name: "stable", URL: "https://charts.helm.sh/stable"},
+	})
+	fl := flags.AddRepoFlags{Username: "user", Password: "secret", ForceUpdate: true}
+	expectedReq, err := json.Marshal(&addRepoRequest{URL: "https://charts.helm.sh/stable", AddRepoFlags: fl, Password: "secret"})
+	require.NoError(t, err)
+	apiclient.On("Send", "http://localhost:8080/repositories/stable", http.MethodPut, bytes.NewBuffer(expectedReq)).Return(httpresponse, apiresponse, nil).Once()
+
+	repo, err := newTestHttpClient(apiclient).AddRepo(context.Background(), "stable", "https://charts.helm.sh/stable", fl)
+
+	assert.NoError(t, err)
+	assert.Equal(t, repository.Repository{Name: "stable", URL: "https://charts.helm.sh/stable"}, repo)
+	assert.JSONEq(t, `{"url":"https://charts.helm.sh/stable","username":"user","password":"secret","force_update":true,"skip_tls_verify":false}`, string(expectedReq))
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientAddRepoAPIOnFailure(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 400, &addRepoResponse{Error: "repository already exists"})
+	apiclient.On("Send", mock.Anything, http.MethodPut, mock.Anything).Return(httpresponse, apiresponse, nil)
+
+	_, err := newTestHttpClient(apiclient).AddRepo(context.Background(), "stable", "https://charts.helm.sh/stable", flags.AddRepoFlags{})
+
+	assert.EqualError(t, err, "AddRepo API returned an error: repository already exists")
+}
+
+func TestHttpClientAddRepoAPIValidatesInput(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpclient := newTestHttpClient(apiclient)
+
+	_, err := httpclient.AddRepo(context.Background(), "", "https://charts.helm.sh/stable", flags.AddRepoFlags{})
+	assert.EqualError(t, err, "name cannot be empty")
+
+	_, err = httpclient.AddRepo(context.Background(), "stable", "", flags.AddRepoFlags{})
+	assert.EqualError(t, err, "url cannot be empty")
+
+	_, err = httpclient.AddRepo(context.Background(), "stable", "https://charts.helm.sh/stable", flags.AddRepoFlags{Password: "secret"})
+	assert.EqualError(t, err, "username is required when a password is set")
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientListReposAPIOnSuccess(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 200, &listReposResponse{
+		Repositories: []repository.Repository{{Name: "stable", URL: "https://charts.helm.sh/stable"}},
+	})
+	apiclient.On("Send", "http://localhost:8080/repositories", http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+	repos, err := newTestHttpClient(apiclient).ListRepos(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, repos, 1)
+	assert.Equal(t, "stable", repos[0].Name)
+}
+
+func TestHttpClientListReposAPIOnNoContent(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	apiclient.On("Send", "http://localhost:8080/repositories", http.MethodGet, nil).Return(&http.Response{StatusCode: 204}, nil, nil)
+
+	repos, err := newTestHttpClient(apiclient).ListRepos(context.Background())
+
+	assert.NoError(t, err)
+	assert.Empty(t, repos)
+}
+
+func TestHttpClientUpdateReposAPI(t *testing.T) {
+	t.Run("When the update succeeds", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 200, &repoResponse{})
+		apiclient.On("Send", "http://localhost:8080/repositories/update", http.MethodPost, bytes.NewBuffer([]byte(`{"names":["stable"]}`))).Return(httpresponse, apiresponse, nil)
+
+		err := newTestHttpClient(apiclient).UpdateRepos(context.Background(), flags.UpdateRepoFlags{Names: []string{"stable"}})
+
+		assert.NoError(t, err)
+		apiclient.AssertExpectations(t)
+	})
+
+	t.Run("When the update fails", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 500, &repoResponse{Error: "index unreachable"})
+		apiclient.On("Send", mock.Anything, http.MethodPost, mock.Anything).Return(httpresponse, apiresponse, nil)
+
+		err := newTestHttpClient(apiclient).UpdateRepos(context.Background(), flags.UpdateRepoFlags{})
+
+		assert.EqualError(t, err, "UpdateRepos API returned an error: index unreachable")
+	})
+}
+
+func TestHttpClientRemoveRepoAPI(t *testing.T) {
+	t.Run("When the repository exists", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 200, &repoResponse{})
+		apiclient.On("Send", "http://localhost:8080/repositories/stable", http.MethodDelete, nil).Return(httpresponse, apiresponse, nil)
+
+		err := newTestHttpClient(apiclient).RemoveRepo(context.Background(), "stable")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("When the repository does not exist", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		apiclient.On("Send", "http://localhost:8080/repositories/unknown", http.MethodDelete, nil).Return(&http.Response{StatusCode: 404}, nil, nil)
+
+		err := newTestHttpClient(apiclient).RemoveRepo(context.Background(), "unknown")
+
+		assert.EqualError(t, err, "no repository found: unknown")
+	})
+
+	t.Run("When the name has to be escaped", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 200, &repoResponse{})
+		apiclient.On("Send", "http://localhost:8080/repositories/my%20charts%3Fv=1%23a", http.MethodDelete, nil).Return(httpresponse, apiresponse, nil)
+
+		err := newTestHttpClient(apiclient).RemoveRepo(context.Background(), "my charts?v=1#a")
+
+		assert.NoError(t, err)
+		apiclient.AssertExpectations(t)
+	})
+
+	t.Run("When the name is invalid", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+
+		assert.EqualError(t, newTestHttpClient(apiclient).RemoveRepo(context.Background(), "update"), `invalid repository name "update": the name is reserved`)
+		assert.EqualError(t, newTestHttpClient(apiclient).RemoveRepo(context.Background(), "bitnami/charts"), `invalid repository name "bitnami/charts": must not contain '/'`)
+		apiclient.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+)
+
+const (
+	// DefaultStatusTimeout is how long SafeUpgrade waits for the release to be deployed,
+	// unless the upgrade flags have a timeout
+	DefaultStatusTimeout = 5 * time.Minute
+
+	// DefaultStatusPollInterval is how often SafeUpgrade polls the status of the release
+	DefaultStatusPollInterval = 2 * time.Second
+
+	// DefaultRollbackTimeout is how long SafeUpgrade waits for a rollback to be done
+	DefaultRollbackTimeout = 5 * time.Minute
+)
+
+// SafeUpgradeHooks are called at the stages of SafeUpgrade. All hooks are optional
+type SafeUpgradeHooks struct {
+	// BeforeUpgrade is called with the revision a failed upgrade would be rolled back to,
+	// nil if there is none
+	BeforeUpgrade func(rollbackTo *release.Release)
+
+	// AfterUpgrade is called with the outcome of the upgrade api
+	AfterUpgrade func(result Result, err error)
+
+	// OnStatus is called with every status polled while waiting for the release
+	OnStatus func(rel release.Release)
+
+	// BeforeRollback is called with the revision the release is rolled back to, and why
+	BeforeRollback func(rollbackTo release.Release, cause error)
+
+	// AfterRollback is called with the outcome of the rollback api
+	AfterRollback func(rel release.Release, err error)
+}
+
+// SafeUpgradeOption configures SafeUpgrade
+type SafeUpgradeOption func(*safeUpgrade)
+
+// WithStatusTimeout sets how long to wait for the release to be deployed after the upgrade
+func WithStatusTimeout(timeout time.Duration) SafeUpgradeOption {
+	return func(s *safeUpgrade) {
+		s.timeout = timeout
+	}
+}
+
+// WithStatusPollInterval sets how often the status of the release is polled
+func WithStatusPollInterval(interval time.Duration) SafeUpgradeOption {
+	return func(s *safeUpgrade) {
+		s.interval = interval
+	}
+}
+
+// WithRollbackTimeout sets how long to wait for the rollback of a failed upgrade.
+// The rollback is not bound to the context of SafeUpgrade, so that it is done even
+// when the context is cancelled while waiting for the release. With Wait, the timeout
+// of the flags is used instead if it is longer
+func WithRollbackTimeout(timeout time.Duration) SafeUpgradeOption {
+	return func(s *safeUpgrade) {
+		s.rollbackTimeout = timeout
+	}
+}
+
+// WithSafeUpgradeHooks sets the hooks called at the stages of the upgrade
+func WithSafeUpgradeHooks(hooks SafeUpgradeHooks) SafeUpgradeOption {
+	return func(s *safeUpgrade) {
+		s.hooks = hooks
+	}
+}
+
+// SafeUpgradeResult is returned by SafeUpgrade
+type SafeUpgradeResult struct {
+	Result
+
+	// Release is the last known state of the release: the deployed upgrade,
+	// or the release created by the rollback
+	Release release.Release
+}
+
+// ErrUpgradeFailed is returned by SafeUpgrade when the upgrade did not end up deployed
+type ErrUpgradeFailed struct {
+	Release string
+	Cause   error
+
+	// RolledBackTo is the revision the release was rolled back to, zero if it was not rolled back
+	RolledBackTo int
+
+	// RollbackErr is the error of the rollback, if it failed
+	RollbackErr error
+}
+
+func (e *ErrUpgradeFailed) Error() string {
+	msg := fmt.Sprintf("upgrade of release %s failed: %s", e.Release, e.Cause)
+	switch {
+	case e.RollbackErr != nil:
+		return fmt.Sprintf("%s; rollback failed: %s", msg, e.RollbackErr)
+	case e.RolledBackTo > 0:
+		return fmt.Sprintf("%s; rolled back to revision %d", msg, e.RolledBackTo)
+	default:
+		return msg + "; no previous revision to roll back to"
+	}
+}
+
+func (e *ErrUpgradeFailed) Unwrap() error {
+	return e.Cause
+}
+
+type safeUpgrade struct {
+	client          Client
+	timeout         time.Duration
+	interval        time.Duration
+	rollbackTimeout time.Duration
+	hooks           SafeUpgradeHooks
+}
+
+// SafeUpgrade upgrades a release and waits for it to be deployed. If the upgrade fails
+// after creating a revision, the release ends up failed, or it is not deployed within
+// the status timeout, the release is rolled back to the last revision that was deployed
+// before the upgrade, picked from its history, and an *ErrUpgradeFailed is returned.
+// The status timeout is the timeout of the flags if set, DefaultStatusTimeout otherwise.
+// The rollback runs on its own context with the rollback timeout, so a release is rolled
+// back even if ctx is cancelled or expires during the upgrade or the wait. With Wait, the
+// rollback timeout is at least the timeout of the flags
+func SafeUpgrade(ctx context.Context, client Client, name string, chart string, values Values, fl flags.UpgradeFlags, opts ...SafeUpgradeOption) (SafeUpgradeResult, error) {
+	s := &safeUpgrade{
+		client:          client,
+		timeout:         DefaultStatusTimeout,
+		interval:        DefaultStatusPollInterval,
+		rollbackTimeout: DefaultRollbackTimeout,
+	}
+	if fl.Timeout > 0 {
+		s.timeout = time.Duration(fl.Timeout) * time.Second
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	// A rollback that waits may take as long as the upgrade was allowed to
+	if fl.Wait && time.Duration(fl.Timeout)*time.Second > s.rollbackTimeout {
+		s.rollbackTimeout = time.Duration(fl.Timeout) * time.Second
+	}
+
+	history, err := client.History(ctx, name, flags.HistoryFlags{CommonFlags: fl.CommonFlags})
+	if err != nil && !(fl.Install && errors.Is(err, ErrReleaseNotFound)) {
+		return SafeUpgradeResult{}, err
+	}
+	rollbackTo := lastDeployed(history)
+	latestRevision := 0
+	if len(history) > 0 {
+		latestRevision = history[len(history)-1].Version
+	}
+	if s.hooks.BeforeUpgrade != nil {
+		s.hooks.BeforeUpgrade(rollbackTo)
+	}
+
+	result, err := client.Upgrade(ctx, name, chart, values, fl)
+	if s.hooks.AfterUpgrade != nil {
+		s.hooks.AfterUpgrade(result, err)
+	}
+	if err != nil {
+		// Only upgrades that created a revision are rolled back, not rejected requests
+		rollbackCtx, cancel := context.WithTimeout(context.Background(), s.rollbackTimeout)
+		defer cancel()
+		current, statusErr := client.Status(rollbackCtx, name, flags.StatusFlags{CommonFlags: fl.CommonFlags})
+		if statusErr != nil || current.Version <= latestRevision {
+			return SafeUpgradeResult{Result: result}, err
+		}
+		return s.rollback(rollbackCtx, name, fl, rollbackTo, SafeUpgradeResult{Result: result, Release: current}, err)
+	}
+	if fl.DryRun {
+		return SafeUpgradeResult{Result: result}, nil
+	}
+
+	current, err := s.wait(ctx, name, fl.CommonFlags)
+	if err != nil {
+		rollbackCtx, cancel := context.WithTimeout(context.Background(), s.rollbackTimeout)
+		defer cancel()
+		return s.rollback(rollbackCtx, name, fl, rollbackTo, SafeUpgradeResult{Result: result, Release: current}, err)
+	}
+	return SafeUpgradeResult{Result: result, Release: current}, nil
+}
+
+// wait polls the status of the release until it is deployed, it ends up in any other
+// status that is not pending, or the status timeout expires
+func (s *safeUpgrade) wait(ctx context.Context, name string, fl flags.CommonFlags) (release.Release, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	var current release.Release
+	var statusErr error
+	for {
+		rel, err := s.client.Status(waitCtx, name, flags.StatusFlags{CommonFlags: fl})
+		if err == nil {
+			current, statusErr = rel, nil
+			if s.hooks.OnStatus != nil {
+				s.hooks.OnStatus(rel)
+			}
+			switch {
+			case rel.Status == "deployed":
+				return rel, nil
+			case !strings.HasPrefix(rel.Status, "pending-"):
+				return rel, fmt.Errorf("release is %s", rel.Status)
+			}
+		} else {
+			statusErr = err
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return current, ctx.Err()
+			}
+			if statusErr != nil {
+				return current, fmt.Errorf("release was not deployed within %s: %s", s.timeout, statusErr)
+			}
+			return current, fmt.Errorf("release was not deployed within %s, last status %s", s.timeout, current.Status)
+		case <-ticker.C:
+		}
+	}
+}
+
+// rollback rolls the release back to the revision, if there is one, and returns the
+// cause of the rollback as an *ErrUpgradeFailed. ctx is the rollback context, not the
+// context of SafeUpgrade
+func (s *safeUpgrade) rollback(ctx context.Context, name string, fl flags.UpgradeFlags, rollbackTo *release.Release, result SafeUpgradeResult, cause error) (SafeUpgradeResult, error) {
+	failed := &ErrUpgradeFailed{Release: name, Cause: cause}
+	if rollbackTo == nil {
+		return result, failed
+	}
+
+	if s.hooks.BeforeRollback != nil {
+		s.hooks.BeforeRollback(*rollbackTo, cause)
+	}
+	rel, err := s.client.Rollback(ctx, name, flags.RollbackFlags{
+		Revision:      rollbackTo.Version,
+		Wait:          fl.Wait,
+		Timeout:       fl.Timeout,
+		CleanupOnFail: fl.CleanupOnFail,
+		CommonFlags:   fl.CommonFlags,
+	})
+	if s.hooks.AfterRollback != nil {
+		s.hooks.AfterRollback(rel, err)
+	}
+	if err != nil {
+		failed.RollbackErr = err
+		return result, failed
+	}
+
+	failed.RolledBackTo = rollbackTo.Version
+	result.Release = rel
+	return result, failed
+}
+
+// lastDeployed returns the latest revision of the history that was deployed
+func lastDeployed(history []release.Release) *release.Release {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Status == "deployed" || history[i].Status == "superseded" {
+			rel := history[i]
+			return &rel
+		}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/gojekfarm/albatross-client-go/api/apitest"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// revision matches the flags of a rollback to the revision
+func revision(revision int) interface{} {
+	return mock.MatchedBy(func(fl flags.RollbackFlags) bool {
+		return fl.Revision == revision
+	})
+}
+
+var safeUpgradeHistory = []release.Release{
+	{Name: "api", Version: 1, Status: "superseded"},
+	{Name: "api", Version: 2, Status: "deployed"},
+}
+
+func fastSafeUpgrade(stages *[]string) []api.SafeUpgradeOption {
+	return []api.SafeUpgradeOption{
+		api.WithStatusPollInterval(time.Millisecond),
+		api.WithStatusTimeout(50 * time.Millisecond),
+		api.WithSafeUpgradeHooks(api.SafeUpgradeHooks{
+			BeforeUpgrade: func(rollbackTo *release.Release) {
+				*stages = append(*stages, "before upgrade")
+			},
+			AfterUpgrade: func(result api.Result, err error) {
+				*stages = append(*stages, "after upgrade")
+			},
+			OnStatus: func(rel release.Release) {
+				*stages = append(*stages, "status "+rel.Status)
+			},
+			BeforeRollback: func(rollbackTo release.Release, cause error) {
+				*stages = append(*stages, "before rollback")
+			},
+			AfterRollback: func(rel release.Release, err error) {
+				*stages = append(*stages, "after rollback")
+			},
+		}),
+	}
+}
+
+func TestSafeUpgradeWaitsForTheReleaseToBeDeployed(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("History", "api", mock.Anything).Return(safeUpgradeHistory, nil)
+	client.On("Upgrade", "api", "stable/api", api.Values(nil), mock.Anything).Return(api.Result{Status: "pending-upgrade", Version: "1.2.0"}, nil)
+	client.On("Status", "api", mock.Anything).Return(release.Release{Version: 3, Status: "pending-upgrade"}, nil).Twice()
+	client.On("Status", "api", mock.Anything).Return(release.Release{Version: 3, Status: "deployed"}, nil).Once()
+	var stages []string
+
+	result, err := api.SafeUpgrade(context.Background(), client, "api", "stable/api", nil, flags.UpgradeFlags{}, fastSafeUpgrade(&stages)...)
+
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", result.Version)
+	assert.Equal(t, release.Release{Version: 3, Status: "deployed"}, result.Release)
+	assert.Equal(t, []string{"before upgrade", "after upgrade", "status pending-upgrade", "status pending-upgrade", "status deployed"}, stages)
+	client.AssertNotCalled(t, "Rollback", mock.Anything, mock.Anything)
+}
+
+func TestSafeUpgradeRollsBackFailedRelease(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("History", "api", mock.Anything).Return(safeUpgradeHistory, nil)
+	client.On("Upgrade", "api", "stable/api", api.Values(nil), mock.Anything).Return(api.Result{Status: "pending-upgrade"}, nil)
+	client.On("Status", "api", mock.Anything).Return(release.Release{Version: 3, Status: "failed"}, nil)
+	client.On("Rollback", "api", revision(2)).Return(release.Release{Version: 4, Status: "deployed"}, nil)
+	var stages []string
+
+	result, err := api.SafeUpgrade(context.Background(), client, "api", "stable/api", nil, flags.UpgradeFlags{}, fastSafeUpgrade(&stages)...)
+
+	var upgradeErr *api.ErrUpgradeFailed
+	require.True(t, errors.As(err, &upgradeErr))
+	assert.Equal(t, 2, upgradeErr.RolledBackTo)
+	assert.EqualError(t, err, "upgrade of release api failed: release is failed; rolled back to revision 2")
+	assert.Equal(t, 4, result.Release.Version)
+	assert.Equal(t, []string{"before upgrade", "after upgrade", "status failed", "before rollback", "after rollback"}, stages)
+}
+
+func TestSafeUpgradeRollsBackWhenTheStatusTimesOut(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("History", "api", mock.Anything).Return([]release.Release{
+		{Name: "api", Version: 1, Status: "superseded"},
+		{Name: "api", Version: 2, Status: "failed"},
+	}, nil)
+	client.On("Upgrade", "api", "stable/api", api.Values(nil), mock.Anything).Return(api.Result{Status: "pending-upgrade"}, nil)
+	client.On("Status", "api", mock.Anything).Return(release.Release{Version: 3, Status: "pending-upgrade"}, nil)
+	client.On("Rollback", "api", revision(1)).Return(release.Release{Version: 4, Status: "deployed"}, nil)
+	var stages []string
+
+	_, err := api.SafeUpgrade(context.Background(), client, "api", "stable/api", nil, flags.UpgradeFlags{}, fastSafeUpgrade(&stages)...)
+
+	assert.EqualError(t, err, "upgrade of release api failed: release was not deployed within 50ms, last status pending-upgrade; rolled back to revision 1")
+	client.AssertCalled(t, "Rollback", "api", revision(1))
+}
+
+func TestSafeUpgradeRollsBackWhenTheUpgradeFailsAfterCreatingARevision(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("History", "api", mock.Anything).Return(safeUpgradeHistory, nil)
+	client.On("Upgrade", "api", "stable/api", api.Values(nil), mock.Anything).Return(api.Result{}, errors.New("Upgrade API returned an error: timed out waiting for the condition"))
+	client.On("Status", "api", mock.Anything).Return(release.Release{Version: 3, Status: "failed"}, nil)
+	client.On("Rollback", "api", revision(2)).Return(release.Release{}, errors.New("Rollback API returned an error: cluster unreachable"))
+	var stages []string
+
+	_, err := api.SafeUpgrade(context.Background(), client, "api", "stable/api", nil, flags.UpgradeFlags{}, fastSafeUpgrade(&stages)...)
+
+	var upgradeErr *api.ErrUpgradeFailed
+	require.True(t, errors.As(err, &upgradeErr))
+	assert.Zero(t, upgradeErr.RolledBackTo)
+	assert.EqualError(t, err, "upgrade of release api failed: Upgrade API returned an error: timed out waiting for the condition; "+
+		"rollback failed: Rollback API returned an error: cluster unreachable")
+}
+
+// contextClient fails status and rollback calls whose context is done, as the http client does
+type contextClient struct {
+	*apitest.Client
+}
+
+func (c contextClient) Status(ctx context.Context, name string, fl flags.StatusFlags) (release.Release, error) {
+	if err := ctx.Err(); err != nil {
+		return release.Release{}, err
+	}
+	return c.Client.Status(ctx, name, fl)
+}
+
+func (c contextClient) Rollback(ctx context.Context, name string, fl flags.RollbackFlags) (release.Release, error) {
+	if err := ctx.Err(); err != nil {
+		return release.Release{}, err
+	}
+	return c.Client.Rollback(ctx, name, fl)
+}
+
+func TestSafeUpgradeRollsBackWhenTheContextIsCancelledDuringTheWait(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("History", "api", mock.Anything).Return(safeUpgradeHistory, nil)
+	client.On("Upgrade", "api", "stable/api", api.Values(nil), mock.Anything).Return(api.Result{Status: "pending-upgrade"}, nil)
+	client.On("Status", "api", mock.Anything).Return(release.Release{Version: 3, Status: "pending-upgrade"}, nil)
+	client.On("Rollback", "api", revision(2)).Return(release.Release{Version: 4, Status: "deployed"}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hooks := api.SafeUpgradeHooks{OnStatus: func(rel release.Release) { cancel() }}
+
+	result, err := api.SafeUpgrade(ctx, contextClient{client}, "api", "stable/api", nil, flags.UpgradeFlags{},
+		api.WithStatusPollInterval(time.Millisecond), api.WithSafeUpgradeHooks(hooks))
+
+	var upgradeErr *api.ErrUpgradeFailed
+	require.True(t, errors.As(err, &upgradeErr))
+	assert.Equal(t, context.Canceled, upgradeErr.Cause)
+	assert.Equal(t, 2, upgradeErr.RolledBackTo)
+	assert.Equal(t, 4, result.Release.Version)
+}
+
+func TestSafeUpgradeRollsBackWhenTheContextIsCancelledDuringTheUpgrade(t *testing.T) {
+	client := new(apitest.Client)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.On("History", "api", mock.Anything).Return(safeUpgradeHistory, nil)
+	client.On("Upgrade", "api", "stable/api", api.Values(nil), mock.Anything).Run(func(mock.Arguments) { cancel() }).Return(api.Result{}, context.Canceled)
+	client.On("Status", "api", mock.Anything).Return(release.Release{Version: 3, Status: "pending-upgrade"}, nil)
+	client.On("Rollback", "api", revision(2)).Return(release.Release{Version: 4, Status: "deployed"}, nil)
+
+	_, err := api.SafeUpgrade(ctx, contextClient{client}, "api", "stable/api", nil, flags.UpgradeFlags{}, api.WithRollbackTimeout(time.Second))
+
+	assert.EqualError(t, err, "upgrade of release api failed: context canceled; rolled back to revision 2")
+}
+
+// deadlineClient records the deadline of the rollback context
+type deadlineClient struct {
+	*apitest.Client
+	deadline time.Time
+}
+
+func (c *deadlineClient) Rollback(ctx context.Context, name string, fl flags.RollbackFlags) (release.Release, error) {
+	c.deadline, _ = ctx.Deadline()
+	return c.Client.Rollback(ctx, name, fl)
+}
+
+func TestSafeUpgradeGivesTheRollbackTheTimeoutOfTheFlagsWithWait(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fl       flags.UpgradeFlags
+		deadline time.Duration
+	}{
+		{"With a longer timeout and wait", flags.UpgradeFlags{Wait: true, Timeout: 900}, 15 * time.Minute},
+		{"With a shorter timeout and wait", flags.UpgradeFlags{Wait: true, Timeout: 60}, api.DefaultRollbackTimeout},
+		{"With a longer timeout without wait", flags.UpgradeFlags{Timeout: 900}, api.DefaultRollbackTimeout},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &deadlineClient{Client: new(apitest.Client)}
+			client.On("History", "api", mock.Anything).Return(safeUpgradeHistory, nil)
+			client.On("Upgrade", "api", "stable/api", api.Values(nil), mock.Anything).Return(api.Result{Status: "failed"}, nil)
+			client.On("Status", "api", mock.Anything).Return(release.Release{Version: 3, Status: "failed"}, nil)
+			client.On("Rollback", "api", mock.Anything).Return(release.Release{Version: 4, Status: "deployed"}, nil)
+			started := time.Now()
+
+			_, err := api.SafeUpgrade(context.Background(), client, "api", "stable/api", nil, tc.fl)
+
+			require.Error(t, err)
+			assert.WithinDuration(t, started.Add(tc.deadline), client.deadline, time.Second)
+		})
+	}
+}
+
+func TestSafeUpgradeDoesNotRollBackRejectedUpgrade(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("History", "api", mock.Anything).Return(safeUpgradeHistory, nil)
+	client.On("Upgrade", "api", "stable/api", api.Values(nil), mock.Anything).Return(api.Result{}, errors.New("Upgrade API returned an error: chart not found"))
+	client.On("Status", "api", mock.Anything).Return(release.Release{Version: 2, Status: "deployed"}, nil)
+	var stages []string
+
+	_, err := api.SafeUpgrade(context.Background(), client, "api", "stable/api", nil, flags.UpgradeFlags{}, fastSafeUpgrade(&stages)...)
+
+	assert.EqualError(t, err, "Upgrade API returned an error: chart not found")
+	client.AssertNotCalled(t, "Rollback", mock.Anything, mock.Anything)
+}
+
+func TestSafeUpgradeInstallsMissingReleaseWithoutRollbackTarget(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("History", "api", mock.Anything).Return(nil, api.ErrReleaseNotFound)
+	client.On("Upgrade", "api", "stable/api", api.Values(nil), mock.Anything).Return(api.Result{Status: "pending-install"}, nil)
+	client.On("Status", "api", mock.Anything).Return(release.Release{Version: 1, Status: "failed"}, nil)
+	var rollbackTo = &release.Release{}
+	hooks := api.WithSafeUpgradeHooks(api.SafeUpgradeHooks{BeforeUpgrade: func(rel *release.Release) { rollbackTo = rel }})
+
+	_, err := api.SafeUpgrade(context.Background(), client, "api", "stable/api", nil, flags.UpgradeFlags{Install: true},
+		api.WithStatusPollInterval(time.Millisecond), hooks)
+
+	assert.Nil(t, rollbackTo)
+	assert.EqualError(t, err, "upgrade of release api failed: release is failed; no previous revision to roll back to")
+	client.AssertNotCalled(t, "Rollback", mock.Anything, mock.Anything)
+}
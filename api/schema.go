@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/jsonschema"
+)
+
+// valuesSchemaResponse is the json schema to parse the values schema api response
+type valuesSchemaResponse struct {
+	Error  string          `json:"error,omitempty"`
+	Schema json.RawMessage `json:"schema,omitempty"`
+}
+
+// ValuesSchema calls the values schema api and returns the parsed values.schema.json of a chart.
+// It returns a nil schema if the chart has none
+func (c *HttpClient) ValuesSchema(ctx context.Context, chartName string, version string) (*jsonschema.Schema, error) {
+	if chartName == "" {
+		return nil, errors.New("chart cannot be empty")
+	}
+
+	queryParams := url.Values{}
+	if version != "" {
+		queryParams.Set("version", version)
+	}
+	reqPath := fmt.Sprintf("/charts/%s/schema", chartName)
+	httpResponse, data, err := c.request(ctx, flags.CommonFlags{}, reqPath, http.MethodGet, nil, queryParams.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if httpResponse.StatusCode == 204 || httpResponse.StatusCode == 404 {
+		return nil, nil
+	}
+
+	var result valuesSchemaResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("ValuesSchema API returned an error: %s", result.Error)
+	}
+	if len(result.Schema) == 0 || string(result.Schema) == "null" {
+		return nil, nil
+	}
+	return jsonschema.Parse(result.Schema)
+}
+
+// validateValues checks the values against the local schema file, if one is given, or
+// against the schema fetched from the server when the client validates values.
+// Charts without a schema are not validated.
+//
+// Helm validates the values after coalescing them with the defaults of the chart, which
+// the client does not have. Keys set to null are left out, as coalescing deletes them, and
+// required properties missing at the root or under objects that are not in the values are
+// not reported, as the defaults may set them. Objects given in the values must have their
+// required properties
+func (c *HttpClient) validateValues(ctx context.Context, chartName string, version string, values Values, schemaFile string) error {
+	var schema *jsonschema.Schema
+	var err error
+	switch {
+	case schemaFile != "":
+		schema, err = jsonschema.LoadFile(schemaFile)
+	case c.schemaValidation:
+		schema, err = c.ValuesSchema(ctx, chartName, version)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error loading the values schema of chart %s: %s", chartName, err)
+	}
+	if schema == nil {
+		return nil
+	}
+
+	supplied := withoutNulls(values)
+	err = schema.Validate(supplied)
+	var errs jsonschema.Errors
+	if !errors.As(err, &errs) {
+		return err
+	}
+	var problems jsonschema.Errors
+	for _, e := range errs {
+		if e.Keyword != "required" || suppliedObject(supplied, e.Path) {
+			problems = append(problems, e)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("values do not match the schema of chart %s: %w", chartName, problems)
+	}
+	return nil
+}
+
+// suppliedObject reports whether the values hold an object at the path of a validation error,
+// such as "image" or "ports.0". The root is not considered supplied, as it is always present
+func suppliedObject(values map[string]interface{}, path string) bool {
+	if path == "" || path == "(root)" {
+		return false
+	}
+	var current interface{} = values
+	for _, segment := range strings.Split(path, ".") {
+		if list, ok := current.([]interface{}); ok {
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(list) {
+				return false
+			}
+			current = list[i]
+			continue
+		}
+		object, ok := asMap(current)
+		if !ok {
+			return false
+		}
+		if current, ok = object[segment]; !ok {
+			return false
+		}
+	}
+	_, ok := asMap(current)
+	return ok
+}
+
+// withoutNulls returns a copy of the values without the keys that are set to null
+func withoutNulls(values Values) map[string]interface{} {
+	copied := map[string]interface{}{}
+	for key, value := range values {
+		if value == nil {
+			continue
+		}
+		if m, ok := asMap(value); ok {
+			value = withoutNulls(m)
+		}
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testValuesSchema = `{
+	"type": "object",
+	"properties": {
+		"replicas": {"type": "integer", "minimum": 1},
+		"image": {"type": "object", "properties": {"tag": {"type": "string"}}}
+	}
+}`
+
+func writeSchemaFile(t *testing.T, schema string) string {
+	dir, err := ioutil.TempDir("", "albatross-schema")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "values.schema.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(schema), 0600))
+	return path
+}
+
+func TestHttpClientValuesSchemaAPI(t *testing.T) {
+	t.Run("When the chart has a schema", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 200, &valuesSchemaResponse{Schema: json.RawMessage(testValuesSchema)})
+		apiclient.On("Send", "http://localhost:8080/charts/stable/api/schema?version=1.2.0", http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+		schema, err := newTestHttpClient(apiclient).ValuesSchema(context.Background(), "stable/api", "1.2.0")
+
+		require.NoError(t, err)
+		require.NotNil(t, schema)
+		assert.EqualError(t, schema.Validate(Values{"replicas": 0}), "replicas: Must be greater than or equal to 1")
+	})
+
+	t.Run("When the chart has no schema", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		apiclient.On("Send", "http://localhost:8080/charts/stable/api/schema", http.MethodGet, nil).Return(&http.Response{StatusCode: 404}, nil, nil)
+
+		schema, err := newTestHttpClient(apiclient).ValuesSchema(context.Background(), "stable/api", "")
+
+		assert.NoError(t, err)
+		assert.Nil(t, schema)
+	})
+
+	t.Run("When the api returns an error", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 500, &valuesSchemaResponse{Error: "chart not found in repository"})
+		apiclient.On("Send", mock.Anything, http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+		_, err := newTestHttpClient(apiclient).ValuesSchema(context.Background(), "stable/api", "")
+
+		assert.EqualError(t, err, "ValuesSchema API returned an error: chart not found in repository")
+	})
+}
+
+func TestHttpClientInstallValidatesValuesAgainstSchemaFile(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	fl := flags.InstallFlags{
+		ValuesSchemaFile: writeSchemaFile(t, testValuesSchema),
+		CommonFlags:      flags.CommonFlags{KubeContext: "staging"},
+	}
+
+	_, err := newTestHttpClient(apiclient).Install(context.Background(), "api", "stable/api", Values{
+		"replicas": "two",
+		"image":    map[string]interface{}{"tag": 2},
+	}, fl)
+
+	var errs jsonschema.Errors
+	require.True(t, errors.As(err, &errs))
+	assert.EqualError(t, err, "values do not match the schema of chart stable/api: "+
+		"image.tag: Invalid type. Expected: string, given: integer; replicas: Invalid type. Expected: integer, given: string")
+	apiclient.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHttpClientUpgradeValidatesValuesAgainstChartSchema(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	schemaResponse, schemaData := jsonResponse(t, 200, &valuesSchemaResponse{Schema: json.RawMessage(testValuesSchema)})
+	apiclient.On("Send", "http://localhost:8080/charts/stable/api/schema?version=1.2.0", http.MethodGet, nil).Return(schemaResponse, schemaData, nil)
+	upgradeResponse, upgradeData := jsonResponse(t, 200, &upgradeResponse{Status: "deployed"})
+	apiclient.On("Send", "http://localhost:8080/clusters/staging/namespaces/default/releases/api", http.MethodPut, mock.Anything).Return(upgradeResponse, upgradeData, nil).Once()
+	client := newTestHttpClient(apiclient)
+	client.schemaValidation = true
+	fl := flags.UpgradeFlags{Version: "1.2.0", CommonFlags: flags.CommonFlags{KubeContext: "staging"}}
+
+	_, err := client.Upgrade(context.Background(), "api", "stable/api", Values{"replicas": 0}, fl)
+	assert.EqualError(t, err, "values do not match the schema of chart stable/api: replicas: Must be greater than or equal to 1")
+
+	result, err := client.Upgrade(context.Background(), "api", "stable/api", Values{"replicas": 2}, fl)
+	assert.NoError(t, err)
+	assert.Equal(t, "deployed", result.Status)
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientValidatesValuesThatTheChartDefaultsComplete(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	schemaResponse, schemaData := jsonResponse(t, 200, &valuesSchemaResponse{Schema: json.RawMessage(`{
+		"type": "object",
+		"required": ["image"],
+		"properties": {
+			"image": {"type": "object", "required": ["repository"], "properties": {"tag": {"type": "string"}}},
+			"service": {"type": "object", "required": ["port"]},
+			"ports": {"type": "array", "items": {"type": "object", "required": ["port"]}},
+			"resources": {"type": "object"}
+		}
+	}`)})
+	apiclient.On("Send", "http://localhost:8080/charts/stable/api/schema?version=1.2.0", http.MethodGet, nil).Return(schemaResponse, schemaData, nil)
+	installResponse, installData := jsonResponse(t, 200, &installResponse{Status: "deployed"})
+	apiclient.On("Send", "http://localhost:8080/clusters/staging/namespaces/default/releases", http.MethodPost, mock.Anything).Return(installResponse, installData, nil).Once()
+	client := newTestHttpClient(apiclient)
+	client.schemaValidation = true
+	fl := flags.InstallFlags{Version: "1.2.0", CommonFlags: flags.CommonFlags{KubeContext: "staging"}}
+
+	// The required image and service may come from the chart defaults, and the null deletes the default resources
+	result, err := client.Install(context.Background(), "api", "stable/api", Values{
+		"replicas":  2,
+		"resources": nil,
+	}, fl)
+	require.NoError(t, err)
+	assert.Equal(t, "deployed", result.Status)
+
+	_, err = client.Install(context.Background(), "api", "stable/api", Values{"image": map[string]interface{}{"repository": "api", "tag": 2}}, fl)
+	assert.EqualError(t, err, "values do not match the schema of chart stable/api: image.tag: Invalid type. Expected: string, given: integer")
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientReportsRequiredPropertiesOfSuppliedObjects(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	client := newTestHttpClient(apiclient)
+	schemaFile := writeSchemaFile(t, `{
+		"type": "object",
+		"required": ["image"],
+		"properties": {
+			"image": {"type": "object", "required": ["repository"]},
+			"ports": {"type": "array", "items": {"type": "object", "required": ["port"]}}
+		}
+	}`)
+	fl := flags.InstallFlags{ValuesSchemaFile: schemaFile, CommonFlags: flags.CommonFlags{KubeContext: "staging"}}
+
+	_, err := client.Install(context.Background(), "api", "stable/api", Values{
+		"image": map[string]interface{}{"tag": "v2"},
+		"ports": []interface{}{map[string]interface{}{"name": "http"}},
+	}, fl)
+
+	assert.EqualError(t, err, "values do not match the schema of chart stable/api: image: repository is required; ports.0: port is required")
+	apiclient.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+}
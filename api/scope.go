@@ -0,0 +1,55 @@
+package api
+
+import "github.com/gojekfarm/albatross-client-go/flags"
+
+// ForCluster returns a copy of the client bound to the kube context. The default kube
+// token and api server of the client are dropped when binding to another cluster
+//
+//	client.ForCluster("staging").InNamespace("payments").List(ctx, flags.ListFlags{})
+func (c *HttpClient) ForCluster(kubeContext string) Client {
+	scoped := *c
+	if kubeContext != c.defaults.KubeContext {
+		scoped.defaults.KubeToken = ""
+		scoped.defaults.KubeAPIServer = ""
+	}
+	scoped.defaults.KubeContext = kubeContext
+	return &scoped
+}
+
+// InNamespace returns a copy of the client bound to the namespace
+func (c *HttpClient) InNamespace(namespace string) Client {
+	scoped := *c
+	scoped.defaults.Namespace = namespace
+	return &scoped
+}
+
+// Scope returns the common flags as the client sends them: left empty values are filled
+// with the defaults of the client, and the namespace with the default namespace
+func (c *HttpClient) Scope(fl flags.CommonFlags) flags.CommonFlags {
+	fl = c.scope(fl)
+	if fl.Namespace == "" {
+		fl.Namespace = flags.DefaultNamespace
+	}
+	return fl
+}
+
+// scope fills the common flags left empty with the defaults of the client.
+// The default credentials are only used for requests to the default kube context
+func (c *HttpClient) scope(fl flags.CommonFlags) flags.CommonFlags {
+	if fl.KubeContext == "" {
+		fl.KubeContext = c.defaults.KubeContext
+	}
+	if fl.Namespace == "" {
+		fl.Namespace = c.defaults.Namespace
+	}
+	if fl.KubeContext != c.defaults.KubeContext {
+		return fl
+	}
+	if fl.KubeToken == "" {
+		fl.KubeToken = c.defaults.KubeToken
+	}
+	if fl.KubeAPIServer == "" {
+		fl.KubeAPIServer = c.defaults.KubeAPIServer
+	}
+	return fl
+}
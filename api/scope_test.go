@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gojekfarm/albatross-client-go/config"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newScopedTestHttpClient(apiclient *mockAPIClient) *HttpClient {
+	client := newTestHttpClient(apiclient)
+	client.defaults = flags.CommonFlags{
+		KubeContext:   "staging",
+		Namespace:     "apps",
+		KubeToken:     "staging-token",
+		KubeAPIServer: "https://kube.staging:6443",
+	}
+	return client
+}
+
+// sentBody returns the body of the only request sent through the mock
+func sentBody(t *testing.T, apiclient *mockAPIClient) string {
+	require.Len(t, apiclient.Calls, 1)
+	return apiclient.Calls[0].Arguments.Get(2).(*bytes.Buffer).String()
+}
+
+func TestHttpClientUsesDefaultsWhenFlagsAreEmpty(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 200, &installResponse{Status: "deployed"})
+	apiclient.On("Send", "http://localhost:8080/clusters/staging/namespaces/apps/releases", http.MethodPost, mock.Anything).Return(httpresponse, apiresponse, nil)
+	client := newScopedTestHttpClient(apiclient)
+
+	_, err := client.Install(context.Background(), "api", "stable/api", nil, flags.InstallFlags{})
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"Chart": "stable/api",
+		"Values": null,
+		"Name": "api",
+		"Flags": {
+			"dry_run": false,
+			"version": "",
+			"kube_token": "staging-token",
+			"kube_apiserver": "https://kube.staging:6443"
+		}
+	}`, sentBody(t, apiclient))
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientFlagsTakePrecedenceOverDefaults(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 200, &upgradeResponse{Status: "deployed"})
+	apiclient.On("Send", "http://localhost:8080/clusters/production/namespaces/payments/releases/api", http.MethodPut, mock.Anything).Return(httpresponse, apiresponse, nil)
+	client := newScopedTestHttpClient(apiclient)
+	fl := flags.UpgradeFlags{CommonFlags: flags.CommonFlags{KubeContext: "production", Namespace: "payments"}}
+
+	_, err := client.Upgrade(context.Background(), "api", "stable/api", nil, fl)
+
+	assert.NoError(t, err)
+	// The default credentials belong to the default cluster and are not sent to another one
+	assert.JSONEq(t, `{
+		"Chart": "stable/api",
+		"Values": null,
+		"Flags": {"dry_run": false, "version": "", "install": false}
+	}`, sentBody(t, apiclient))
+	apiclient.AssertExpectations(t)
+}
+
+func TestScopedClient(t *testing.T) {
+	t.Run("ForCluster and InNamespace bind the client", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 200, &listResponse{Releases: []release.Release{{Name: "api"}}})
+		apiclient.On("Send", "http://localhost:8080/clusters/production/namespaces/payments/releases", http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+		client := newScopedTestHttpClient(apiclient)
+
+		releases, err := client.ForCluster("production").InNamespace("payments").List(context.Background(), flags.ListFlags{})
+
+		assert.NoError(t, err)
+		assert.Len(t, releases, 1)
+		assert.Equal(t, "staging", client.defaults.KubeContext)
+		apiclient.AssertExpectations(t)
+	})
+
+	t.Run("ForCluster drops the default credentials of another cluster", func(t *testing.T) {
+		scoped := newScopedTestHttpClient(new(mockAPIClient)).ForCluster("production").(*HttpClient)
+
+		assert.Equal(t, flags.CommonFlags{KubeContext: "production", Namespace: "apps"}, scoped.defaults)
+	})
+
+	t.Run("ForCluster keeps the default credentials of the default cluster", func(t *testing.T) {
+		scoped := newScopedTestHttpClient(new(mockAPIClient)).ForCluster("staging").(*HttpClient)
+
+		assert.Equal(t, "staging-token", scoped.defaults.KubeToken.Reveal())
+	})
+
+	t.Run("Scope fills the flags as they are sent", func(t *testing.T) {
+		client := newScopedTestHttpClient(new(mockAPIClient))
+
+		assert.Equal(t, flags.CommonFlags{KubeContext: "production", Namespace: "default"},
+			Scope(client.ForCluster("production").InNamespace(""), flags.CommonFlags{}))
+		assert.Equal(t, "apps", client.Scope(flags.CommonFlags{}).Namespace)
+	})
+
+	t.Run("Scope returns the flags of clients that do not fill them", func(t *testing.T) {
+		fl := flags.CommonFlags{KubeContext: "staging"}
+
+		assert.Equal(t, fl, Scope(struct{ Client }{}, fl))
+	})
+
+	t.Run("The default namespace does not apply when listing all namespaces", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 200, &listResponse{})
+		apiclient.On("Send", "http://localhost:8080/clusters/staging/releases", http.MethodGet, nil).Return(httpresponse, apiresponse, nil)
+
+		client := newScopedTestHttpClient(apiclient)
+		client.credentialsInHeaders = true
+
+		_, err := client.List(context.Background(), flags.ListFlags{AllNamespaces: true})
+
+		assert.NoError(t, err)
+		apiclient.AssertExpectations(t)
+	})
+}
+
+func TestNewClientAppliesConfigDefaults(t *testing.T) {
+	client, err := NewClient("http://localhost:8080",
+		config.WithKubeContext("staging"),
+		config.WithNamespace("apps"),
+		config.WithKubeToken("staging-token"),
+		config.WithKubeAPIServer("https://kube.staging:6443"),
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, flags.CommonFlags{
+		KubeContext:   "staging",
+		Namespace:     "apps",
+		KubeToken:     "staging-token",
+		KubeAPIServer: "https://kube.staging:6443",
+	}, client.(*HttpClient).defaults)
+}
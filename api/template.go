@@ -0,0 +1,69 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/manifest"
+)
+
+// templateRequest is the json schema for the template api
+type templateRequest struct {
+	Name   string
+	Chart  string
+	Values Values
+	Flags  flags.TemplateFlags
+}
+
+// templateResponse is the json schema to parse the template api response
+type templateResponse struct {
+	Error    string `json:"error,omitempty"`
+	Manifest string `json:"manifest,omitempty"`
+}
+
+// Template calls the template api and parses the rendered manifest
+func (c *HttpClient) Template(ctx context.Context, name string, chart string, values Values, fl flags.TemplateFlags) ([]manifest.Document, error) {
+	if fl.Namespace == "" {
+		fl.Namespace = c.defaults.Namespace
+	}
+	fl = fl.WithDefaults()
+	if err := flags.Join(flags.ValidReleaseName(name), fl.Valid()); err != nil {
+		return nil, err
+	}
+
+	version, err := c.resolveVersion(ctx, chart, fl.Version)
+	if err != nil {
+		return nil, err
+	}
+	fl.Version = version
+
+	reqBody, err := json.Marshal(&templateRequest{
+		Name:   name,
+		Chart:  chart,
+		Values: values,
+		Flags:  fl,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, data, err := c.request(ctx, flags.CommonFlags{}, "/template", http.MethodPost, bytes.NewBuffer(reqBody), "")
+	if err != nil {
+		return nil, err
+	}
+
+	var result templateResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Error != "" {
+		return nil, fmt.Errorf("Template API returned an error: %s", result.Error)
+	}
+
+	return manifest.Parse(result.Manifest)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpClientTemplateAPIOnSuccess(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 200, &templateResponse{
+		Manifest: "---\n# Source: api/templates/service.yaml\napiVersion: v1\nkind: Service\nmetadata:\n  name: api\n",
+	})
+	fl := flags.TemplateFlags{
+		Version:     "1.2.0",
+		KubeVersion: "1.20.0",
+		APIVersions: []string{"monitoring.coreos.com/v1"},
+	}
+	expectedFlags := fl
+	expectedFlags.Namespace = "default"
+	expectedReq, err := json.Marshal(&templateRequest{Name: "api", Chart: "charts/api", Values: Values{"replicas": 2}, Flags: expectedFlags})
+	require.NoError(t, err)
+	apiclient.On("Send", "http://localhost:8080/template", http.MethodPost, bytes.NewBuffer(expectedReq)).Return(httpresponse, apiresponse, nil)
+
+	docs, err := newTestHttpClient(apiclient).Template(context.Background(), "api", "charts/api", Values{"replicas": 2}, fl)
+
+	assert.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "Service", docs[0].Kind)
+	assert.Equal(t, "api", docs[0].Name)
+	assert.Equal(t, "api/templates/service.yaml", docs[0].Source)
+	assert.JSONEq(t, `{"version":"1.2.0","namespace":"default","kube_version":"1.20.0","api_versions":["monitoring.coreos.com/v1"],"include_crds":false}`, string(mustMarshal(t, expectedFlags)))
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientTemplateAPIOnFailure(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 400, &templateResponse{Error: "chart not found"})
+	apiclient.On("Send", mock.Anything, http.MethodPost, mock.Anything).Return(httpresponse, apiresponse, nil)
+
+	_, err := newTestHttpClient(apiclient).Template(context.Background(), "api", "charts/api", nil, flags.TemplateFlags{})
+
+	assert.EqualError(t, err, "Template API returned an error: chart not found")
+}
+
+func TestHttpClientTemplateAPIValidatesInput(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpclient := newTestHttpClient(apiclient)
+
+	_, err := httpclient.Template(context.Background(), "", "charts/api", nil, flags.TemplateFlags{})
+	assert.EqualError(t, err, "name cannot be empty")
+
+	_, err = httpclient.Template(context.Background(), "api", "charts/api", nil, flags.TemplateFlags{KubeVersion: "latest"})
+	assert.Error(t, err)
+	apiclient.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
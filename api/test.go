@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+)
+
+// ErrTestFailed is returned by Test when at least one test pod did not succeed
+type ErrTestFailed struct {
+	Release string
+	Failed  []release.TestResult
+}
+
+func (e *ErrTestFailed) Error() string {
+	names := make([]string, len(e.Failed))
+	for i, result := range e.Failed {
+		names[i] = fmt.Sprintf("%s (%s)", result.Name, result.Phase)
+	}
+	return fmt.Sprintf("tests failed for release %s: %s", e.Release, strings.Join(names, ", "))
+}
+
+// testRequest is the json schema for the release test api
+type testRequest struct {
+	Flags testFlags
+}
+
+// testFlags are the flags of the release test api along with the kube credentials
+type testFlags struct {
+	flags.TestFlags
+	kubeCredentials
+}
+
+// testResponse is the json schema to parse the release test api response
+type testResponse struct {
+	Error string               `json:"error,omitempty"`
+	Tests []release.TestResult `json:"tests,omitempty"`
+}
+
+// Test calls the release test api and returns the results of the test pods
+func (c *HttpClient) Test(ctx context.Context, name string, fl flags.TestFlags) ([]release.TestResult, error) {
+	fl.CommonFlags = c.scope(fl.CommonFlags)
+	fl = fl.WithDefaults()
+	if err := flags.Join(flags.ValidReleaseName(name), fl.Valid()); err != nil {
+		return nil, err
+	}
+	reqBody, err := json.Marshal(&testRequest{Flags: testFlags{fl, c.bodyCredentials(fl.CommonFlags)}})
+	if err != nil {
+		return nil, err
+	}
+	reqPath := fmt.Sprintf("/clusters/%s/namespaces/%s/releases/%s/test", fl.KubeContext, fl.Namespace, name)
+
+	httpResponse, data, err := c.request(ctx, fl.CommonFlags, reqPath, http.MethodPost, bytes.NewBuffer(reqBody), "")
+	if err != nil {
+		return nil, err
+	}
+	if httpResponse.StatusCode == 404 {
+		return nil, fmt.Errorf("%w: %s", ErrReleaseNotFound, name)
+	}
+
+	var result testResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Error != "" {
+		return result.Tests, fmt.Errorf("Test API returned an error: %s", result.Error)
+	}
+
+	var failed []release.TestResult
+	for _, test := range result.Tests {
+		if test.Failed() {
+			failed = append(failed, test)
+		}
+	}
+	if len(failed) > 0 {
+		return result.Tests, &ErrTestFailed{Release: name, Failed: failed}
+	}
+
+	return result.Tests, nil
+}
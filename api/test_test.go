@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpClientTestAPIOnSuccess(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	started := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	httpresponse, apiresponse := jsonResponse(t, 200, &testResponse{
+		Tests: []release.TestResult{
+			{Name: "api-test-connection", Phase: "Succeeded", Started: started, Completed: started.Add(5 * time.Second), Logs: "ok"},
+		},
+	})
+	fl := flags.TestFlags{
+		Timeout:     300,
+		Logs:        true,
+		CommonFlags: flags.CommonFlags{KubeContext: "staging", Namespace: "apps"},
+	}
+	expectedReq := mustMarshal(t, &testRequest{Flags: testFlags{TestFlags: fl}})
+	apiclient.On("Send", "http://localhost:8080/clusters/staging/namespaces/apps/releases/api/test", http.MethodPost, bytes.NewBuffer(expectedReq)).Return(httpresponse, apiresponse, nil)
+
+	results, err := newTestHttpClient(apiclient).Test(context.Background(), "api", fl)
+
+	assert.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "Succeeded", results[0].Phase)
+	assert.Equal(t, started.Add(5*time.Second), results[0].Completed)
+	assert.Equal(t, "ok", results[0].Logs)
+	assert.JSONEq(t, `{"Flags":{"timeout":300,"logs":true}}`, string(expectedReq))
+	apiclient.AssertExpectations(t)
+}
+
+func TestHttpClientTestAPIWhenATestFails(t *testing.T) {
+	apiclient := new(mockAPIClient)
+	httpresponse, apiresponse := jsonResponse(t, 200, &testResponse{
+		Tests: []release.TestResult{
+			{Name: "api-test-connection", Phase: "Succeeded"},
+			{Name: "api-test-migrations", Phase: "Failed"},
+		},
+	})
+	apiclient.On("Send", mock.Anything, http.MethodPost, mock.Anything).Return(httpresponse, apiresponse, nil)
+	fl := flags.TestFlags{CommonFlags: flags.CommonFlags{KubeContext: "staging"}}
+
+	results, err := newTestHttpClient(apiclient).Test(context.Background(), "api", fl)
+
+	assert.Len(t, results, 2)
+	var testErr *ErrTestFailed
+	require.True(t, errors.As(err, &testErr))
+	assert.Equal(t, "api", testErr.Release)
+	assert.Len(t, testErr.Failed, 1)
+	assert.EqualError(t, err, "tests failed for release api: api-test-migrations (Failed)")
+}
+
+func TestHttpClientTestAPIOnFailure(t *testing.T) {
+	t.Run("When the api returns an error", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		httpresponse, apiresponse := jsonResponse(t, 500, &testResponse{Error: "timed out waiting for the condition"})
+		apiclient.On("Send", mock.Anything, http.MethodPost, mock.Anything).Return(httpresponse, apiresponse, nil)
+
+		_, err := newTestHttpClient(apiclient).Test(context.Background(), "api", flags.TestFlags{CommonFlags: flags.CommonFlags{KubeContext: "staging"}})
+
+		assert.EqualError(t, err, "Test API returned an error: timed out waiting for the condition")
+	})
+
+	t.Run("When the release does not exist", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+		apiclient.On("Send", mock.Anything, http.MethodPost, mock.Anything).Return(&http.Response{StatusCode: 404}, nil, nil)
+
+		_, err := newTestHttpClient(apiclient).Test(context.Background(), "api", flags.TestFlags{CommonFlags: flags.CommonFlags{KubeContext: "staging"}})
+
+		assert.EqualError(t, err, "no release found: api")
+	})
+
+	t.Run("When the flags are invalid", func(t *testing.T) {
+		apiclient := new(mockAPIClient)
+
+		_, err := newTestHttpClient(apiclient).Test(context.Background(), "api", flags.TestFlags{Timeout: -1, CommonFlags: flags.CommonFlags{KubeContext: "staging"}})
+
+		assert.EqualError(t, err, "timeout cannot be negative")
+		apiclient.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
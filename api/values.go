@@ -0,0 +1,358 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Paths address nested values with dots between keys and brackets for list indexes,
+// e.g. "image.tag" or "ports[0].port". Dots, brackets and backslashes that are part
+// of a key are escaped with a backslash, e.g. "annotations.kubernetes\.io/ingress\.class"
+
+// pathSegment is a map key or a list index of a path
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parsePath splits a path into its segments
+func parsePath(path string) ([]pathSegment, error) {
+	if path == "" {
+		return nil, errors.New("path cannot be empty")
+	}
+
+	var segments []pathSegment
+	var key strings.Builder
+	expectKey := true
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '\\':
+			if i+1 == len(path) {
+				return nil, fmt.Errorf("invalid path %q: trailing backslash", path)
+			}
+			i++
+			key.WriteByte(path[i])
+		case '.', '[':
+			if expectKey {
+				if key.Len() == 0 {
+					return nil, fmt.Errorf("invalid path %q: empty key", path)
+				}
+				segments = append(segments, pathSegment{key: key.String()})
+				key.Reset()
+			} else if key.Len() > 0 {
+				return nil, fmt.Errorf("invalid path %q: unexpected %q after index", path, key.String())
+			}
+			expectKey = c == '.'
+			if c == '[' {
+				end := strings.IndexByte(path[i:], ']')
+				if end < 0 {
+					return nil, fmt.Errorf("invalid path %q: unclosed bracket", path)
+				}
+				index, err := strconv.Atoi(path[i+1 : i+end])
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid path %q: invalid index %q", path, path[i+1:i+end])
+				}
+				segments = append(segments, pathSegment{index: index, isIndex: true})
+				i += end
+			}
+		default:
+			key.WriteByte(c)
+		}
+	}
+	if expectKey {
+		if key.Len() == 0 {
+			return nil, fmt.Errorf("invalid path %q: empty key", path)
+		}
+		segments = append(segments, pathSegment{key: key.String()})
+	} else if key.Len() > 0 {
+		return nil, fmt.Errorf("invalid path %q: unexpected %q after index", path, key.String())
+	}
+	return segments, nil
+}
+
+// escapeKey escapes a map key so that it can be used as a segment of a path
+func escapeKey(key string) string {
+	return strings.NewReplacer(`\`, `\\`, ".", `\.`, "[", `\[`, "]", `\]`).Replace(key)
+}
+
+// Get returns the value at the path, and whether it is set
+func (v Values) Get(path string) (interface{}, bool) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var current interface{} = v
+	for _, segment := range segments {
+		if segment.isIndex {
+			list, ok := current.([]interface{})
+			if !ok || segment.index >= len(list) {
+				return nil, false
+			}
+			current = list[segment.index]
+			continue
+		}
+		m, ok := asMap(current)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[segment.key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// Set sets the value at the path, creating the maps and growing the lists on the way.
+// Lists are padded with nulls up to the index. It fails if the path crosses a value
+// that is not a map or a list as expected
+func (v Values) Set(path string, value interface{}) error {
+	if v == nil {
+		return errors.New("cannot set a value on nil values")
+	}
+	segments, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	_, err = set(v, segments, value, "")
+	return err
+}
+
+// set sets the value in current and returns current, which is a new map or list
+// if current had to be created, grown or converted
+func set(current interface{}, segments []pathSegment, value interface{}, path string) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+
+	segment := segments[0]
+	if segment.isIndex {
+		list, ok := current.([]interface{})
+		if current != nil && !ok {
+			return nil, fmt.Errorf("cannot set %s[%d]: %s is not a list", path, segment.index, describePath(path))
+		}
+		for len(list) <= segment.index {
+			list = append(list, nil)
+		}
+		element, err := set(list[segment.index], segments[1:], value, fmt.Sprintf("%s[%d]", path, segment.index))
+		if err != nil {
+			return nil, err
+		}
+		list[segment.index] = element
+		return list, nil
+	}
+
+	m, ok := asMap(current)
+	if current == nil {
+		m, ok = map[string]interface{}{}, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("cannot set %s: %s is not a map", joinPath(path, segment.key), describePath(path))
+	}
+	element, err := set(m[segment.key], segments[1:], value, joinPath(path, segment.key))
+	if err != nil {
+		return nil, err
+	}
+	m[segment.key] = element
+	return m, nil
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return escapeKey(key)
+	}
+	return path + "." + escapeKey(key)
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "the root"
+	}
+	return path
+}
+
+// Merge returns a copy of the values with the overrides deep merged in order, the way
+// helm merges values files: values of later overrides win, maps are merged key by key,
+// and lists and other values are replaced. A null value replaces the key with null, so
+// that it reaches the server and deletes the default of the chart
+func (v Values) Merge(overrides ...Values) Values {
+	merged := normalize(map[string]interface{}(v)).(map[string]interface{})
+	for _, override := range overrides {
+		mergeInto(merged, override, false)
+	}
+	return merged
+}
+
+// Coalesce is like Merge, except that a null value deletes the key, the way helm
+// coalesces values with the defaults of the chart or the values it reuses
+func (v Values) Coalesce(overrides ...Values) Values {
+	merged := normalize(map[string]interface{}(v)).(map[string]interface{})
+	for _, override := range overrides {
+		mergeInto(merged, override, true)
+	}
+	return merged
+}
+
+func mergeInto(dst map[string]interface{}, src map[string]interface{}, deleteNulls bool) {
+	for key, value := range src {
+		if value == nil && deleteNulls {
+			delete(dst, key)
+			continue
+		}
+		srcMap, srcIsMap := asMap(value)
+		if !srcIsMap {
+			dst[key] = normalize(value)
+			continue
+		}
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if !dstIsMap {
+			dstMap = map[string]interface{}{}
+			dst[key] = dstMap
+		}
+		mergeInto(dstMap, srcMap, deleteNulls)
+	}
+}
+
+// MarshalJSON marshals the values after converting the maps with interface{} keys,
+// that yaml decoders produce, into maps with string keys
+func (v Values) MarshalJSON() ([]byte, error) {
+	if v == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(normalize(map[string]interface{}(v)))
+}
+
+// normalize returns a deep copy of the value in which all maps are map[string]interface{}
+// and all lists []interface{}. Keys that are not strings are formatted with fmt
+func normalize(value interface{}) interface{} {
+	switch value := value.(type) {
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, element := range value {
+			list[i] = normalize(element)
+		}
+		return list
+	default:
+		m, ok := asMap(value)
+		if !ok {
+			return value
+		}
+		copied := make(map[string]interface{}, len(m))
+		for key, element := range m {
+			copied[key] = normalize(element)
+		}
+		return copied
+	}
+}
+
+// asMap returns nested values as a plain map. Maps with interface{} keys are converted
+func asMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case Values:
+		return m, true
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(m))
+		for key, element := range m {
+			converted[fmt.Sprint(key)] = element
+		}
+		return converted, true
+	default:
+		return nil, false
+	}
+}
+
+// ValueChangeType is the kind of difference between two values
+type ValueChangeType string
+
+const (
+	ValueAdded   ValueChangeType = "added"
+	ValueRemoved ValueChangeType = "removed"
+	ValueChanged ValueChangeType = "changed"
+)
+
+// ValueChange is a single difference between two Values
+type ValueChange struct {
+	Type ValueChangeType
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// String returns the change in a human readable form, e.g. "~ image.tag: 1.0 -> 1.1"
+func (c ValueChange) String() string {
+	switch c.Type {
+	case ValueAdded:
+		return fmt.Sprintf("+ %s: %s", c.Path, formatValue(c.New))
+	case ValueRemoved:
+		return fmt.Sprintf("- %s: %s", c.Path, formatValue(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, formatValue(c.Old), formatValue(c.New))
+	}
+}
+
+func formatValue(value interface{}) string {
+	data, err := json.Marshal(normalize(value))
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// Diff returns the changes from the values to other, sorted by path. Maps are compared
+// key by key, while lists and other values are compared as a whole. Values are compared
+// by their json form, so that numbers decoded from yaml and json compare equal
+func (v Values) Diff(other Values) []ValueChange {
+	var changes []ValueChange
+	diff(map[string]interface{}(v), map[string]interface{}(other), "", &changes)
+	return changes
+}
+
+func diff(old map[string]interface{}, new map[string]interface{}, path string, changes *[]ValueChange) {
+	keys := make([]string, 0, len(old)+len(new))
+	for key := range old {
+		keys = append(keys, key)
+	}
+	for key := range new {
+		if _, ok := old[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyPath := joinPath(path, key)
+		oldValue, inOld := old[key]
+		newValue, inNew := new[key]
+		switch {
+		case !inOld:
+			*changes = append(*changes, ValueChange{Type: ValueAdded, Path: keyPath, New: newValue})
+		case !inNew:
+			*changes = append(*changes, ValueChange{Type: ValueRemoved, Path: keyPath, Old: oldValue})
+		default:
+			oldMap, oldIsMap := asMap(oldValue)
+			newMap, newIsMap := asMap(newValue)
+			if oldIsMap && newIsMap {
+				diff(oldMap, newMap, keyPath, changes)
+			} else if !equalValues(oldValue, newValue) {
+				*changes = append(*changes, ValueChange{Type: ValueChanged, Path: keyPath, Old: oldValue, New: newValue})
+			}
+		}
+	}
+}
+
+func equalValues(a interface{}, b interface{}) bool {
+	dataA, errA := json.Marshal(normalize(a))
+	dataB, errB := json.Marshal(normalize(b))
+	if errA != nil || errB != nil {
+		return false
+	}
+	return bytes.Equal(dataA, dataB)
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func testValues() Values {
+	return Values{
+		"image": map[string]interface{}{"repository": "api", "tag": "1.0"},
+		"ports": []interface{}{map[string]interface{}{"port": 80}},
+		"annotations": map[interface{}]interface{}{
+			"kubernetes.io/ingress.class": "nginx",
+		},
+	}
+}
+
+func TestValuesGet(t *testing.T) {
+	values := testValues()
+
+	tests := []struct {
+		path  string
+		value interface{}
+		found bool
+	}{
+		{"image.tag", "1.0", true},
+		{"ports[0].port", 80, true},
+		{`annotations.kubernetes\.io/ingress\.class`, "nginx", true},
+		{"image", map[string]interface{}{"repository": "api", "tag": "1.0"}, true},
+		{"image.digest", nil, false},
+		{"ports[1].port", nil, false},
+		{"image.tag.major", nil, false},
+		{"image..tag", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			value, found := values.Get(tt.path)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.value, value)
+		})
+	}
+}
+
+func TestValuesSet(t *testing.T) {
+	values := testValues()
+
+	require.NoError(t, values.Set("image.tag", "1.1"))
+	require.NoError(t, values.Set("resources.limits.cpu", "500m"))
+	require.NoError(t, values.Set("ports[2].port", 443))
+	require.NoError(t, values.Set(`annotations.prometheus\.io/scrape`, "true"))
+
+	assert.Equal(t, Values{
+		"image":     map[string]interface{}{"repository": "api", "tag": "1.1"},
+		"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "500m"}},
+		"ports": []interface{}{
+			map[string]interface{}{"port": 80},
+			nil,
+			map[string]interface{}{"port": 443},
+		},
+		"annotations": map[string]interface{}{
+			"kubernetes.io/ingress.class": "nginx",
+			"prometheus.io/scrape":        "true",
+		},
+	}, values)
+
+	assert.EqualError(t, values.Set("image.tag.major", 1), "cannot set image.tag.major: image.tag is not a map")
+	assert.EqualError(t, values.Set("image[0]", 1), "cannot set image[0]: image is not a list")
+	assert.EqualError(t, values.Set("ports[x]", 1), `invalid path "ports[x]": invalid index "x"`)
+	assert.EqualError(t, values.Set("ports[0]port", 1), `invalid path "ports[0]port": unexpected "port" after index`)
+	assert.EqualError(t, Values(nil).Set("a", 1), "cannot set a value on nil values")
+}
+
+func TestValuesMerge(t *testing.T) {
+	base := Values{
+		"replicas": 1,
+		"image":    map[string]interface{}{"repository": "api", "tag": "1.0"},
+		"env":      []interface{}{"A=1", "B=2"},
+		"debug":    map[string]interface{}{"enabled": true},
+	}
+	override := Values{
+		"image": map[interface{}]interface{}{"tag": "1.1"},
+		"env":   []interface{}{"C=3"},
+		"debug": nil,
+		"ingress": map[string]interface{}{
+			"enabled": true,
+			"tls":     nil,
+		},
+	}
+
+	merged := base.Merge(override, Values{"replicas": 3})
+
+	assert.Equal(t, Values{
+		"replicas": 3,
+		"image":    map[string]interface{}{"repository": "api", "tag": "1.1"},
+		"env":      []interface{}{"C=3"},
+		"debug":    nil,
+		"ingress":  map[string]interface{}{"enabled": true, "tls": nil},
+	}, merged)
+	assert.Equal(t, "1.0", base["image"].(map[string]interface{})["tag"], "base values must not change")
+	assert.Equal(t, map[string]interface{}{"enabled": true}, base["debug"], "base values must not change")
+	assert.Equal(t, Values{}, Values(nil).Merge())
+
+	data, err := json.Marshal(merged)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"debug":null`, "nulls must reach the server to delete chart defaults")
+}
+
+func TestValuesCoalesce(t *testing.T) {
+	base := Values{
+		"image": map[string]interface{}{"repository": "api", "tag": "1.0"},
+		"debug": map[string]interface{}{"enabled": true},
+	}
+
+	coalesced := base.Coalesce(Values{
+		"image": map[string]interface{}{"tag": nil},
+		"debug": nil,
+	})
+
+	assert.Equal(t, Values{"image": map[string]interface{}{"repository": "api"}}, coalesced)
+	assert.Contains(t, base, "debug", "base values must not change")
+}
+
+func TestValuesMarshalJSONConvertsYAMLMaps(t *testing.T) {
+	values := Values{
+		"image": map[interface{}]interface{}{"tag": "1.0", 1: "one"},
+		"ports": []interface{}{map[interface{}]interface{}{"port": 80}},
+	}
+
+	data, err := json.Marshal(values)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"image":{"tag":"1.0","1":"one"},"ports":[{"port":80}]}`, string(data))
+
+	data, err = json.Marshal(struct{ Values Values }{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Values":null}`, string(data))
+}
+
+func TestValuesDiff(t *testing.T) {
+	var deployed Values
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"replicas": 2,
+		"image": {"repository": "api", "tag": "1.0"},
+		"ports": [{"port": 80}],
+		"debug": true
+	}`), &deployed))
+	var desired Values
+	require.NoError(t, yaml.Unmarshal([]byte(`
+replicas: 2
+image:
+  repository: api
+  tag: "1.1"
+ports:
+  - port: 80
+  - port: 443
+ingress:
+  enabled: true
+`), &desired))
+
+	changes := deployed.Diff(desired)
+
+	assert.Equal(t, []ValueChange{
+		{Type: ValueRemoved, Path: "debug", Old: true},
+		{Type: ValueChanged, Path: "image.tag", Old: "1.0", New: "1.1"},
+		{Type: ValueAdded, Path: "ingress", New: Values{"enabled": true}},
+		{Type: ValueChanged, Path: "ports", Old: []interface{}{map[string]interface{}{"port": float64(80)}}, New: []interface{}{Values{"port": 80}, Values{"port": 443}}},
+	}, changes)
+	assert.Equal(t, "~ image.tag: \"1.0\" -> \"1.1\"", changes[1].String())
+	assert.Equal(t, "+ ingress: {\"enabled\":true}", changes[2].String())
+	assert.Empty(t, deployed.Diff(deployed.Merge()))
+}
@@ -0,0 +1,270 @@
+// Package audit records who changed which release, by wrapping an api.Client so that
+// a hook is called after every mutating call, whether it succeeded or not.
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/logger"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/gojekfarm/albatross-client-go/repository"
+)
+
+// Operation is the mutating api call an entry records
+type Operation string
+
+const (
+	Install     Operation = "install"
+	Upgrade     Operation = "upgrade"
+	Uninstall   Operation = "uninstall"
+	Rollback    Operation = "rollback"
+	AddRepo     Operation = "add_repo"
+	UpdateRepos Operation = "update_repos"
+	RemoveRepo  Operation = "remove_repo"
+)
+
+// Result is the outcome of an operation
+type Result string
+
+const (
+	Success Result = "success"
+	Failure Result = "failure"
+)
+
+// Entry is the record of a single mutating call
+type Entry struct {
+	// Time is when the call started
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor,omitempty"`
+	Operation Operation `json:"operation"`
+
+	KubeContext string `json:"kube_context,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Release     string `json:"release,omitempty"`
+	Chart       string `json:"chart,omitempty"`
+
+	// Version is the chart version that was deployed, or the requested version if the call failed
+	Version string `json:"version,omitempty"`
+
+	// Revision is the revision a release was rolled back to
+	Revision int `json:"revision,omitempty"`
+
+	// ValuesHash is the HMAC-SHA256 of the values in json, keyed with the hash key of the
+	// client, so that values can be compared without recording secrets. It is empty
+	// if the client has no hash key
+	ValuesHash string `json:"values_hash,omitempty"`
+
+	// Repository is the name of the chart repositories of repository operations.
+	// It is empty when all repositories are updated
+	Repository string `json:"repository,omitempty"`
+
+	DryRun bool   `json:"dry_run,omitempty"`
+	Result Result `json:"result"`
+
+	// Status is the status of the release returned by the api
+	Status   string        `json:"status,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Hook records audit entries
+type Hook interface {
+	Record(ctx context.Context, entry Entry) error
+}
+
+// HookFunc is a function used as a Hook
+type HookFunc func(ctx context.Context, entry Entry) error
+
+// Record calls the function
+func (f HookFunc) Record(ctx context.Context, entry Entry) error {
+	return f(ctx, entry)
+}
+
+// HashValues returns the HMAC-SHA256 of the values in json keyed with the key, prefixed
+// with "hmac-sha256:", or an empty string if there are no values or no key. The key keeps
+// anyone who reads the audit log from confirming guesses of the values, such as secrets
+func HashValues(values api.Values, key []byte) string {
+	if values == nil || len(key) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Option represents the contract of an audited client modifier function
+type Option func(client *auditedClient)
+
+// WithActor sets who the entries are recorded for. Defaults to the user running the process
+func WithActor(actor string) Option {
+	return func(client *auditedClient) {
+		client.actor = actor
+	}
+}
+
+// WithHashKey sets the secret key the values are hashed with in the entries.
+// Without a key, the hash of the values is not recorded
+func WithHashKey(key []byte) Option {
+	return func(client *auditedClient) {
+		client.hashKey = key
+	}
+}
+
+// WithLogger sets the logger errors of the hook are logged with
+func WithLogger(logger logger.Logger) Option {
+	return func(client *auditedClient) {
+		client.logger = logger
+	}
+}
+
+// auditedClient calls the hook after the mutating calls of the wrapped client
+type auditedClient struct {
+	api.Client
+	hook    Hook
+	actor   string
+	hashKey []byte
+	logger  logger.Logger
+}
+
+// Wrap returns a client that calls the hook after every install, upgrade, uninstall,
+// rollback and repository change. Errors of the hook are logged and do not fail the call.
+// Clients returned by ForCluster and InNamespace are audited as well
+func Wrap(client api.Client, hook Hook, opts ...Option) api.Client {
+	audited := &auditedClient{
+		Client: client,
+		hook:   hook,
+		actor:  currentUser(),
+		logger: &logger.DefaultLogger{},
+	}
+	for _, opt := range opts {
+		opt(audited)
+	}
+	return audited
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func (c *auditedClient) Install(ctx context.Context, name string, chart string, values api.Values, fl flags.InstallFlags) (api.Result, error) {
+	entry := c.entry(Install, fl.CommonFlags, name)
+	result, err := c.Client.Install(ctx, name, chart, values, fl)
+	entry.Chart, entry.ValuesHash, entry.DryRun, entry.Status = chart, HashValues(values, c.hashKey), fl.DryRun, result.Status
+	entry.Version = result.Version
+	if entry.Version == "" {
+		entry.Version = fl.Version
+	}
+	c.record(ctx, entry, err)
+	return result, err
+}
+
+func (c *auditedClient) Upgrade(ctx context.Context, name string, chart string, values api.Values, fl flags.UpgradeFlags) (api.Result, error) {
+	entry := c.entry(Upgrade, fl.CommonFlags, name)
+	result, err := c.Client.Upgrade(ctx, name, chart, values, fl)
+	entry.Chart, entry.ValuesHash, entry.DryRun, entry.Status = chart, HashValues(values, c.hashKey), fl.DryRun, result.Status
+	entry.Version = result.Version
+	if entry.Version == "" {
+		entry.Version = fl.Version
+	}
+	c.record(ctx, entry, err)
+	return result, err
+}
+
+func (c *auditedClient) Uninstall(ctx context.Context, name string, fl flags.UninstallFlags) (release.Release, error) {
+	entry := c.entry(Uninstall, fl.CommonFlags, name)
+	rel, err := c.Client.Uninstall(ctx, name, fl)
+	entry.Chart, entry.DryRun, entry.Status = rel.Chart, fl.DryRun, rel.Status
+	c.record(ctx, entry, err)
+	return rel, err
+}
+
+func (c *auditedClient) Rollback(ctx context.Context, name string, fl flags.RollbackFlags) (release.Release, error) {
+	entry := c.entry(Rollback, fl.CommonFlags, name)
+	rel, err := c.Client.Rollback(ctx, name, fl)
+	entry.Chart, entry.Revision, entry.DryRun, entry.Status = rel.Chart, fl.Revision, fl.DryRun, rel.Status
+	c.record(ctx, entry, err)
+	return rel, err
+}
+
+func (c *auditedClient) AddRepo(ctx context.Context, name string, url string, fl flags.AddRepoFlags) (repository.Repository, error) {
+	entry := Entry{Time: time.Now(), Actor: c.actor, Operation: AddRepo, Repository: name}
+	repo, err := c.Client.AddRepo(ctx, name, url, fl)
+	c.record(ctx, entry, err)
+	return repo, err
+}
+
+func (c *auditedClient) UpdateRepos(ctx context.Context, fl flags.UpdateRepoFlags) error {
+	entry := Entry{Time: time.Now(), Actor: c.actor, Operation: UpdateRepos, Repository: strings.Join(fl.Names, ",")}
+	err := c.Client.UpdateRepos(ctx, fl)
+	c.record(ctx, entry, err)
+	return err
+}
+
+func (c *auditedClient) RemoveRepo(ctx context.Context, name string) error {
+	entry := Entry{Time: time.Now(), Actor: c.actor, Operation: RemoveRepo, Repository: name}
+	err := c.Client.RemoveRepo(ctx, name)
+	c.record(ctx, entry, err)
+	return err
+}
+
+// ForCluster returns the audited client of the wrapped client bound to the kube context
+func (c *auditedClient) ForCluster(kubeContext string) api.Client {
+	scoped := *c
+	scoped.Client = c.Client.ForCluster(kubeContext)
+	return &scoped
+}
+
+// InNamespace returns the audited client of the wrapped client bound to the namespace
+func (c *auditedClient) InNamespace(namespace string) api.Client {
+	scoped := *c
+	scoped.Client = c.Client.InNamespace(namespace)
+	return &scoped
+}
+
+// Scope returns the common flags as the wrapped client sends them
+func (c *auditedClient) Scope(fl flags.CommonFlags) flags.CommonFlags {
+	return api.Scope(c.Client, fl)
+}
+
+// entry starts the entry of a release operation, with the cluster and namespace
+// the wrapped client sends the request to
+func (c *auditedClient) entry(operation Operation, fl flags.CommonFlags, name string) Entry {
+	fl = c.Scope(fl)
+	return Entry{
+		Time:        time.Now(),
+		Actor:       c.actor,
+		Operation:   operation,
+		KubeContext: fl.KubeContext,
+		Namespace:   fl.Namespace,
+		Release:     name,
+	}
+}
+
+func (c *auditedClient) record(ctx context.Context, entry Entry, err error) {
+	entry.Duration = time.Since(entry.Time)
+	entry.Result = Success
+	if err != nil {
+		entry.Result = Failure
+		entry.Error = err.Error()
+	}
+	if hookErr := c.hook.Record(ctx, entry); hookErr != nil {
+		c.logger.Errorf("Error recording audit entry for %s of %s: %s", entry.Operation, entry.Release+entry.Repository, hookErr)
+	}
+}
@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/gojekfarm/albatross-client-go/api/apitest"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	entries []Entry
+}
+
+func (r *recorder) Record(ctx context.Context, entry Entry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func TestWrapRecordsMutatingCalls(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("Install", "api", "stable/api", mock.Anything, mock.Anything).Return(api.Result{Status: "deployed", Version: "1.2.3"}, nil)
+	client.On("Upgrade", "api", "stable/api", mock.Anything, mock.Anything).Return(api.Result{}, errors.New("Upgrade API returned an error: timed out"))
+	client.On("Uninstall", "worker", mock.Anything).Return(release.Release{Name: "worker", Chart: "worker-0.1.0", Status: "uninstalled"}, nil)
+	client.On("RemoveRepo", "stable").Return(nil)
+	hook := &recorder{}
+	audited := Wrap(client.ForCluster("staging"), hook, WithActor("ci"), WithHashKey([]byte("audit-key")))
+	values := api.Values{"replicas": 2}
+
+	_, err := audited.Install(context.Background(), "api", "stable/api", values, flags.InstallFlags{Version: "~1.2"})
+	require.NoError(t, err)
+	_, err = audited.Upgrade(context.Background(), "api", "stable/api", values, flags.UpgradeFlags{
+		Version:     "1.3.0",
+		CommonFlags: flags.CommonFlags{Namespace: "apps"},
+	})
+	require.Error(t, err)
+	_, err = audited.ForCluster("production").Uninstall(context.Background(), "worker", flags.UninstallFlags{})
+	require.NoError(t, err)
+	require.NoError(t, audited.RemoveRepo(context.Background(), "stable"))
+
+	require.Len(t, hook.entries, 4)
+	for i := range hook.entries {
+		assert.False(t, hook.entries[i].Time.IsZero())
+		hook.entries[i].Time, hook.entries[i].Duration = hook.entries[0].Time, 0
+	}
+	started := hook.entries[0].Time
+	hash := HashValues(values, []byte("audit-key"))
+	assert.Equal(t, []Entry{
+		{Time: started, Actor: "ci", Operation: Install, KubeContext: "staging", Namespace: "default", Release: "api",
+			Chart: "stable/api", Version: "1.2.3", ValuesHash: hash, Result: Success, Status: "deployed"},
+		{Time: started, Actor: "ci", Operation: Upgrade, KubeContext: "staging", Namespace: "apps", Release: "api",
+			Chart: "stable/api", Version: "1.3.0", ValuesHash: hash, Result: Failure, Error: "Upgrade API returned an error: timed out"},
+		{Time: started, Actor: "ci", Operation: Uninstall, KubeContext: "production", Namespace: "default", Release: "worker",
+			Chart: "worker-0.1.0", Result: Success, Status: "uninstalled"},
+		{Time: started, Actor: "ci", Operation: RemoveRepo, Repository: "stable", Result: Success},
+	}, hook.entries)
+	assert.Equal(t, flags.CommonFlags{KubeContext: "staging", Namespace: "default"}, api.Scope(audited, flags.CommonFlags{}))
+}
+
+func TestWrapDoesNotFailCallsWhenTheHookFails(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("RemoveRepo", "stable").Return(nil)
+	logger := &mockLogger{}
+	audited := Wrap(client.ForCluster("staging"), HookFunc(func(ctx context.Context, entry Entry) error {
+		return errors.New("disk full")
+	}), WithLogger(logger))
+
+	assert.NoError(t, audited.RemoveRepo(context.Background(), "stable"))
+	assert.Equal(t, []string{"Error recording audit entry for remove_repo of stable: disk full"}, logger.errors)
+}
+
+func TestHashValues(t *testing.T) {
+	key := []byte("audit-key")
+	assert.Empty(t, HashValues(nil, key))
+	assert.Empty(t, HashValues(api.Values{"replicas": 2}, nil))
+	assert.Equal(t,
+		HashValues(api.Values{"image": map[interface{}]interface{}{"tag": "1.0"}, "replicas": 2}, key),
+		HashValues(api.Values{"replicas": 2, "image": api.Values{"tag": "1.0"}}, key))
+	assert.NotEqual(t, HashValues(api.Values{"replicas": 2}, key), HashValues(api.Values{"replicas": 3}, key))
+	assert.NotEqual(t, HashValues(api.Values{"replicas": 2}, key), HashValues(api.Values{"replicas": 2}, []byte("other-key")))
+	assert.Regexp(t, "^hmac-sha256:[0-9a-f]{64}$", HashValues(api.Values{}, key))
+}
+
+func TestWrapDoesNotHashValuesWithoutAKey(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("Install", "api", "stable/api", mock.Anything, mock.Anything).Return(api.Result{Status: "deployed"}, nil)
+	hook := &recorder{}
+
+	_, err := Wrap(client, hook).Install(context.Background(), "api", "stable/api", api.Values{"password": "s3cret"}, flags.InstallFlags{})
+
+	require.NoError(t, err)
+	require.Len(t, hook.entries, 1)
+	assert.Empty(t, hook.entries[0].ValuesHash)
+}
+
+func TestFileSinkAppendsJSONLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "albatross-audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	for i := 0; i < 2; i++ {
+		sink, err := NewFileSink(path)
+		require.NoError(t, err)
+		require.NoError(t, sink.Record(context.Background(), Entry{Operation: Install, Release: fmt.Sprintf("api-%d", i), Result: Success}))
+		require.NoError(t, sink.Close())
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var releases []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		releases = append(releases, entry.Release)
+	}
+	assert.Equal(t, []string{"api-0", "api-1"}, releases)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+type mockLogger struct {
+	errors []string
+}
+
+func (l *mockLogger) Debugf(format string, args ...interface{}) {}
+func (l *mockLogger) Infof(format string, args ...interface{})  {}
+func (l *mockLogger) Errorf(format string, args ...interface{}) {
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+func (l *mockLogger) Fatalf(format string, args ...interface{}) {}
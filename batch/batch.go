@@ -0,0 +1,356 @@
+// Package batch runs install, upgrade and uninstall operations for many releases
+// through an api.Client, with bounded concurrency and dependency ordering.
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+)
+
+// Action is the api call an operation makes
+type Action string
+
+const (
+	Install   Action = "install"
+	Upgrade   Action = "upgrade"
+	Uninstall Action = "uninstall"
+)
+
+// Policy determines what happens to the rest of the batch when an operation fails
+type Policy string
+
+const (
+	// FailFast stops starting new operations after the first failure.
+	// Operations that are already running are allowed to finish
+	FailFast Policy = "fail_fast"
+
+	// ContinueOnError keeps running all operations that do not depend on a failed one
+	ContinueOnError Policy = "continue_on_error"
+)
+
+// ErrSkipped is wrapped by the error of every operation that was not run
+var ErrSkipped = errors.New("operation skipped")
+
+// Operation is a single install, upgrade or uninstall of a release.
+// Only the flags of the operation's action are used
+type Operation struct {
+	// ID identifies the operation within the batch and is referenced by DependsOn.
+	// Defaults to Name, and must be set when the same release name is used more than once,
+	// e.g. in different clusters
+	ID string
+
+	Action Action
+	Name   string
+	Chart  string
+	Values api.Values
+
+	InstallFlags   flags.InstallFlags
+	UpgradeFlags   flags.UpgradeFlags
+	UninstallFlags flags.UninstallFlags
+
+	// DependsOn holds the IDs of operations that must succeed before this one is started
+	DependsOn []string
+}
+
+// Key returns the ID of the operation, or its name if no ID is set
+func (o Operation) Key() string {
+	if o.ID != "" {
+		return o.ID
+	}
+	return o.Name
+}
+
+// Result is the outcome of a single operation
+type Result struct {
+	Operation Operation
+
+	// Status and Version are returned by install and upgrade. Version is the chart version
+	// that was installed, resolved from the version constraint of the operation if it had one
+	Status  string
+	Version string
+
+	// Release is returned by uninstall
+	Release release.Release
+
+	// Err is set if the operation failed or was skipped
+	Err error
+
+	Started  time.Time
+	Duration time.Duration
+}
+
+// Skipped reports whether the operation was not run
+func (r Result) Skipped() bool {
+	return errors.Is(r.Err, ErrSkipped)
+}
+
+// Report holds the results of all operations, in the order they were passed to Run
+type Report struct {
+	Results []Result
+}
+
+// Succeeded reports whether all operations were run without errors
+func (r *Report) Succeeded() bool {
+	for _, result := range r.Results {
+		if result.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the results of operations that were run and returned an error
+func (r *Report) Failed() []Result {
+	var failed []Result
+	for _, result := range r.Results {
+		if result.Err != nil && !result.Skipped() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Skipped returns the results of operations that were not run
+func (r *Report) Skipped() []Result {
+	var skipped []Result
+	for _, result := range r.Results {
+		if result.Skipped() {
+			skipped = append(skipped, result)
+		}
+	}
+	return skipped
+}
+
+// Option represents the contract of a runner modifier function
+type Option func(runner *Runner)
+
+// WithConcurrency sets the maximum number of operations running at the same time
+func WithConcurrency(concurrency int) Option {
+	return func(runner *Runner) {
+		runner.concurrency = concurrency
+	}
+}
+
+// WithPolicy sets the failure policy of the runner
+func WithPolicy(policy Policy) Option {
+	return func(runner *Runner) {
+		runner.policy = policy
+	}
+}
+
+// Runner runs batches of operations against an api client
+type Runner struct {
+	client      api.Client
+	concurrency int
+	policy      Policy
+}
+
+// NewRunner returns a runner with a concurrency of 5 and the FailFast policy,
+// unless configured otherwise by the options
+func NewRunner(client api.Client, opts ...Option) *Runner {
+	runner := &Runner{
+		client:      client,
+		concurrency: 5,
+		policy:      FailFast,
+	}
+	for _, opt := range opts {
+		opt(runner)
+	}
+	if runner.concurrency <= 0 {
+		runner.concurrency = 1
+	}
+	return runner
+}
+
+// Run runs the operations, starting each one once all of its dependencies have succeeded.
+// Operations that depend on a failed or skipped operation are skipped.
+// An error is returned without running anything if the batch is invalid, e.g. if it
+// has duplicate IDs, unknown dependencies or dependency cycles. Failures of single
+// operations are only reported in the Report
+func (r *Runner) Run(ctx context.Context, ops []Operation) (*Report, error) {
+	graph, err := newGraph(ops)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Results: make([]Result, len(ops))}
+	finished := make([]bool, len(ops))
+	pending := make([]int, len(ops))
+	var ready []int
+	for i := range ops {
+		pending[i] = len(graph.dependencies[i])
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	// skip marks an operation and all operations depending on it as skipped
+	var skip func(i int, reason error)
+	skip = func(i int, reason error) {
+		if finished[i] {
+			return
+		}
+		finished[i] = true
+		report.Results[i] = Result{Operation: ops[i], Err: reason}
+		for _, dependent := range graph.dependents[i] {
+			skip(dependent, fmt.Errorf("%w: dependency %q did not succeed", ErrSkipped, ops[i].Key()))
+		}
+	}
+
+	done := make(chan int)
+	running := 0
+	stopped := false
+	for {
+		if ctx.Err() != nil {
+			stopped = true
+		}
+		for !stopped && len(ready) > 0 && running < r.concurrency {
+			i := ready[0]
+			ready = ready[1:]
+			if finished[i] {
+				continue
+			}
+			running++
+			go func(i int) {
+				report.Results[i] = r.run(ctx, ops[i])
+				done <- i
+			}(i)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		i := <-done
+		running--
+		finished[i] = true
+
+		if report.Results[i].Err != nil {
+			if r.policy == FailFast {
+				stopped = true
+			}
+			for _, dependent := range graph.dependents[i] {
+				skip(dependent, fmt.Errorf("%w: dependency %q did not succeed", ErrSkipped, ops[i].Key()))
+			}
+			continue
+		}
+
+		for _, dependent := range graph.dependents[i] {
+			pending[dependent]--
+			if pending[dependent] == 0 && !finished[dependent] {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	reason := fmt.Errorf("%w: batch was stopped after a failure", ErrSkipped)
+	if ctx.Err() != nil {
+		reason = fmt.Errorf("%w: %s", ErrSkipped, ctx.Err())
+	}
+	for i := range ops {
+		if !finished[i] {
+			skip(i, reason)
+		}
+	}
+
+	return report, nil
+}
+
+// run makes the api call of a single operation
+func (r *Runner) run(ctx context.Context, op Operation) Result {
+	result := Result{Operation: op, Started: time.Now()}
+
+	var deployed api.Result
+	switch op.Action {
+	case Install:
+		deployed, result.Err = r.client.Install(ctx, op.Name, op.Chart, op.Values, op.InstallFlags)
+		result.Status, result.Version = deployed.Status, deployed.Version
+	case Upgrade:
+		deployed, result.Err = r.client.Upgrade(ctx, op.Name, op.Chart, op.Values, op.UpgradeFlags)
+		result.Status, result.Version = deployed.Status, deployed.Version
+	case Uninstall:
+		result.Release, result.Err = r.client.Uninstall(ctx, op.Name, op.UninstallFlags)
+	}
+
+	result.Duration = time.Since(result.Started)
+	return result
+}
+
+// graph holds the dependency edges between operations, by index
+type graph struct {
+	dependencies [][]int
+	dependents   [][]int
+}
+
+func newGraph(ops []Operation) (*graph, error) {
+	index := map[string]int{}
+	for i, op := range ops {
+		switch op.Action {
+		case Install, Upgrade, Uninstall:
+		default:
+			return nil, fmt.Errorf("invalid action %q for operation %q", op.Action, op.Key())
+		}
+		if op.Key() == "" {
+			return nil, fmt.Errorf("operation %d has no name", i)
+		}
+		if _, ok := index[op.Key()]; ok {
+			return nil, fmt.Errorf("duplicate operation %q", op.Key())
+		}
+		index[op.Key()] = i
+	}
+
+	g := &graph{
+		dependencies: make([][]int, len(ops)),
+		dependents:   make([][]int, len(ops)),
+	}
+	for i, op := range ops {
+		for _, dep := range op.DependsOn {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("operation %q depends on unknown operation %q", op.Key(), dep)
+			}
+			g.dependencies[i] = append(g.dependencies[i], j)
+			g.dependents[j] = append(g.dependents[j], i)
+		}
+	}
+
+	if cycle := g.findCycle(ops); cycle != "" {
+		return nil, fmt.Errorf("dependency cycle at operation %q", cycle)
+	}
+	return g, nil
+}
+
+// findCycle returns the key of an operation that is part of a dependency cycle, if any
+func (g *graph) findCycle(ops []Operation) string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(ops))
+
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		state[i] = visiting
+		for _, j := range g.dependencies[i] {
+			if state[j] == visiting || (state[j] == unvisited && visit(j)) {
+				return true
+			}
+		}
+		state[i] = visited
+		return false
+	}
+
+	for i := range ops {
+		if state[i] == unvisited && visit(i) {
+			return ops[i].Key()
+		}
+	}
+	return ""
+}
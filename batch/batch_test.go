@@ -0,0 +1,170 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/gojekfarm/albatross-client-go/api/apitest"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// order returns the releases operated on, in the order the calls were made
+func order(client *apitest.Client) []string {
+	var names []string
+	for _, call := range client.Calls {
+		names = append(names, call.Arguments.String(0))
+	}
+	return names
+}
+
+func TestRunnerRunsOperationsInDependencyOrder(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("Install", "database", "stable/postgres", mock.Anything, mock.Anything).Return(api.Result{Status: "deployed"}, nil)
+	client.On("Upgrade", "api", "charts/api", mock.Anything, mock.Anything).Return(api.Result{Status: "deployed"}, nil)
+	client.On("Upgrade", "worker", "charts/worker", mock.Anything, mock.Anything).Return(api.Result{Status: "deployed"}, nil)
+	client.On("Uninstall", "legacy", mock.Anything).Return(release.Release{Name: "legacy", Status: "uninstalled"}, nil)
+
+	ops := []Operation{
+		{Action: Upgrade, Name: "api", Chart: "charts/api", DependsOn: []string{"database"}},
+		{Action: Upgrade, Name: "worker", Chart: "charts/worker", DependsOn: []string{"database", "api"}},
+		{Action: Install, Name: "database", Chart: "stable/postgres"},
+		{Action: Uninstall, Name: "legacy", DependsOn: []string{"worker"}},
+	}
+
+	report, err := NewRunner(client, WithConcurrency(3)).Run(context.Background(), ops)
+
+	require.NoError(t, err)
+	assert.True(t, report.Succeeded())
+	assert.Equal(t, []string{"database", "api", "worker", "legacy"}, order(client))
+	assert.Equal(t, "api", report.Results[0].Operation.Name)
+	assert.Equal(t, "deployed", report.Results[0].Status)
+	assert.Equal(t, "uninstalled", report.Results[3].Release.Status)
+	client.AssertExpectations(t)
+}
+
+func TestRunnerBoundsConcurrency(t *testing.T) {
+	client := new(apitest.Client)
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	client.On("Install", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}).Return(api.Result{Status: "deployed"}, nil)
+
+	var ops []Operation
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		ops = append(ops, Operation{Action: Install, Name: name})
+	}
+
+	report, err := NewRunner(client, WithConcurrency(2)).Run(context.Background(), ops)
+
+	require.NoError(t, err)
+	assert.True(t, report.Succeeded())
+	assert.Equal(t, 2, maxRunning)
+}
+
+func TestRunnerFailurePolicies(t *testing.T) {
+	ops := []Operation{
+		{Action: Install, Name: "database"},
+		{Action: Install, Name: "api", DependsOn: []string{"database"}},
+		{Action: Install, Name: "frontend", DependsOn: []string{"api"}},
+		{Action: Install, Name: "monitoring", DependsOn: []string{"cache"}},
+		{Action: Install, Name: "cache"},
+	}
+
+	t.Run("When the policy is continue on error", func(t *testing.T) {
+		client := new(apitest.Client)
+		client.On("Install", "database", "", mock.Anything, mock.Anything).Return(api.Result{}, errors.New("Install API returned an error: timed out"))
+		client.On("Install", mock.Anything, "", mock.Anything, mock.Anything).Return(api.Result{Status: "deployed"}, nil)
+
+		report, err := NewRunner(client, WithConcurrency(1), WithPolicy(ContinueOnError)).Run(context.Background(), ops)
+
+		require.NoError(t, err)
+		assert.False(t, report.Succeeded())
+		require.Len(t, report.Failed(), 1)
+		assert.Equal(t, "database", report.Failed()[0].Operation.Name)
+		require.Len(t, report.Skipped(), 2)
+		assert.EqualError(t, report.Results[1].Err, `operation skipped: dependency "database" did not succeed`)
+		assert.EqualError(t, report.Results[2].Err, `operation skipped: dependency "api" did not succeed`)
+		assert.NoError(t, report.Results[3].Err)
+		assert.NoError(t, report.Results[4].Err)
+	})
+
+	t.Run("When the policy is fail fast", func(t *testing.T) {
+		client := new(apitest.Client)
+		client.On("Install", "database", "", mock.Anything, mock.Anything).Return(api.Result{}, errors.New("Install API returned an error: timed out"))
+
+		report, err := NewRunner(client, WithConcurrency(1)).Run(context.Background(), ops)
+
+		require.NoError(t, err)
+		assert.Len(t, report.Failed(), 1)
+		assert.Len(t, report.Skipped(), 4)
+		assert.EqualError(t, report.Results[4].Err, "operation skipped: batch was stopped after a failure")
+		client.AssertNumberOfCalls(t, "Install", 1)
+	})
+}
+
+func TestRunnerSkipsOperationsWhenContextIsDone(t *testing.T) {
+	client := new(apitest.Client)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := NewRunner(client).Run(ctx, []Operation{{Action: Install, Name: "api"}})
+
+	require.NoError(t, err)
+	assert.EqualError(t, report.Results[0].Err, "operation skipped: context canceled")
+	client.AssertNotCalled(t, "Install", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunnerRejectsInvalidBatches(t *testing.T) {
+	testCases := []struct {
+		name string
+		ops  []Operation
+		err  string
+	}{
+		{"invalid action", []Operation{{Action: "rollback", Name: "api"}}, `invalid action "rollback" for operation "api"`},
+		{"duplicate operation", []Operation{{Action: Install, Name: "api"}, {Action: Upgrade, Name: "api"}}, `duplicate operation "api"`},
+		{"unknown dependency", []Operation{{Action: Install, Name: "api", DependsOn: []string{"db"}}}, `operation "api" depends on unknown operation "db"`},
+		{"dependency cycle", []Operation{
+			{Action: Install, Name: "api", DependsOn: []string{"worker"}},
+			{Action: Install, Name: "worker", DependsOn: []string{"api"}},
+		}, `dependency cycle at operation "api"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := new(apitest.Client)
+			_, err := NewRunner(client).Run(context.Background(), tc.ops)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestRunnerAllowsSameReleaseInDifferentClusters(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("Upgrade", "api", "charts/api", mock.Anything, mock.Anything).Return(api.Result{Status: "deployed"}, nil).Twice()
+
+	report, err := NewRunner(client).Run(context.Background(), []Operation{
+		{ID: "staging/api", Action: Upgrade, Name: "api", Chart: "charts/api"},
+		{ID: "production/api", Action: Upgrade, Name: "api", Chart: "charts/api", DependsOn: []string{"staging/api"}},
+	})
+
+	require.NoError(t, err)
+	assert.True(t, report.Succeeded())
+	client.AssertExpectations(t)
+}
@@ -0,0 +1,282 @@
+// Package cache wraps an api.Client to cache the releases returned by the list, status
+// and history apis, for callers such as dashboards that read the same releases often.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+)
+
+const (
+	// DefaultTTL is how long a response is served from the cache
+	DefaultTTL = 5 * time.Second
+
+	// DefaultMaxEntries is the maximum number of cached responses
+	DefaultMaxEntries = 1000
+)
+
+// Option represents the contract of a cached client modifier function
+type Option func(store *store)
+
+// WithTTL sets how long a response is served from the cache before it is revalidated
+func WithTTL(ttl time.Duration) Option {
+	return func(store *store) {
+		store.ttl = ttl
+	}
+}
+
+// WithMaxEntries sets the maximum number of cached responses. The least recently
+// used responses are evicted first
+func WithMaxEntries(maxEntries int) Option {
+	return func(store *store) {
+		store.maxEntries = maxEntries
+	}
+}
+
+// Wrap returns a client that caches the responses of List, Status and History.
+// Responses are served from the cache until the ttl expires; after that they are
+// revalidated with the entity tag of the response, if the server sent one, and fetched
+// again otherwise. Concurrent identical requests share a single api call.
+// Install, Upgrade, Uninstall and Rollback drop all cached responses of their cluster.
+// Clients returned by ForCluster and InNamespace share the cache
+func Wrap(client api.Client, opts ...Option) api.Client {
+	s := &store{
+		ttl:        DefaultTTL,
+		maxEntries: DefaultMaxEntries,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+		calls:      map[string]*call{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.maxEntries <= 0 {
+		s.maxEntries = 1
+	}
+	return &cachedClient{Client: client, store: s}
+}
+
+type cachedClient struct {
+	api.Client
+	store *store
+}
+
+func (c *cachedClient) List(ctx context.Context, fl flags.ListFlags) ([]release.Release, error) {
+	v, err := c.store.get(ctx, c.key("list", "", fl.CommonFlags, fl), func(ctx context.Context) (interface{}, error) {
+		return c.Client.List(ctx, fl)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]release.Release(nil), v.([]release.Release)...), nil
+}
+
+func (c *cachedClient) Status(ctx context.Context, name string, fl flags.StatusFlags) (release.Release, error) {
+	v, err := c.store.get(ctx, c.key("status", name, fl.CommonFlags, fl), func(ctx context.Context) (interface{}, error) {
+		return c.Client.Status(ctx, name, fl)
+	})
+	if err != nil {
+		return release.Release{}, err
+	}
+	return v.(release.Release), nil
+}
+
+func (c *cachedClient) History(ctx context.Context, name string, fl flags.HistoryFlags) ([]release.Release, error) {
+	v, err := c.store.get(ctx, c.key("history", name, fl.CommonFlags, fl), func(ctx context.Context) (interface{}, error) {
+		return c.Client.History(ctx, name, fl)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]release.Release(nil), v.([]release.Release)...), nil
+}
+
+func (c *cachedClient) Install(ctx context.Context, name string, chart string, values api.Values, fl flags.InstallFlags) (api.Result, error) {
+	defer c.invalidate(fl.CommonFlags)
+	return c.Client.Install(ctx, name, chart, values, fl)
+}
+
+func (c *cachedClient) Upgrade(ctx context.Context, name string, chart string, values api.Values, fl flags.UpgradeFlags) (api.Result, error) {
+	defer c.invalidate(fl.CommonFlags)
+	return c.Client.Upgrade(ctx, name, chart, values, fl)
+}
+
+func (c *cachedClient) Uninstall(ctx context.Context, name string, fl flags.UninstallFlags) (release.Release, error) {
+	defer c.invalidate(fl.CommonFlags)
+	return c.Client.Uninstall(ctx, name, fl)
+}
+
+func (c *cachedClient) Rollback(ctx context.Context, name string, fl flags.RollbackFlags) (release.Release, error) {
+	defer c.invalidate(fl.CommonFlags)
+	return c.Client.Rollback(ctx, name, fl)
+}
+
+// ForCluster returns the cached client of the wrapped client bound to the kube context
+func (c *cachedClient) ForCluster(kubeContext string) api.Client {
+	return &cachedClient{Client: c.Client.ForCluster(kubeContext), store: c.store}
+}
+
+// InNamespace returns the cached client of the wrapped client bound to the namespace
+func (c *cachedClient) InNamespace(namespace string) api.Client {
+	return &cachedClient{Client: c.Client.InNamespace(namespace), store: c.store}
+}
+
+// Scope returns the common flags as the wrapped client sends them
+func (c *cachedClient) Scope(fl flags.CommonFlags) flags.CommonFlags {
+	return api.Scope(c.Client, fl)
+}
+
+// key identifies a request by the flags as they are sent. Credentials are part of
+// the key, so that callers never get responses fetched with the credentials of others
+func (c *cachedClient) key(operation string, name string, common flags.CommonFlags, fl interface{}) cacheKey {
+	common = c.Scope(common)
+	// Common flags are not marshaled, the other flags of the request are
+	data, _ := json.Marshal(fl)
+	token := sha256.Sum256([]byte(common.KubeToken.Reveal()))
+	return cacheKey{
+		kubeContext: common.KubeContext,
+		key: strings.Join([]string{
+			operation, common.KubeContext, common.Namespace, name,
+			hex.EncodeToString(token[:]), common.KubeAPIServer, string(data),
+		}, "\x00"),
+	}
+}
+
+func (c *cachedClient) invalidate(common flags.CommonFlags) {
+	c.store.invalidate(c.Scope(common).KubeContext)
+}
+
+type cacheKey struct {
+	kubeContext string
+	key         string
+}
+
+// entry is a cached response. Expired entries are kept to be revalidated with their etag
+type entry struct {
+	key     cacheKey
+	value   interface{}
+	etag    string
+	expires time.Time
+}
+
+// call is an api call in flight, shared by identical requests
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+
+	// cancelled is set if the context of the caller that made the call was done
+	cancelled bool
+}
+
+type store struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	calls   map[string]*call
+
+	// generation is incremented by every invalidation, so that responses fetched
+	// before it are not cached, and requests made after it do not share their call
+	generation uint64
+}
+
+// get returns the cached response of the key, or fetches it. The fetch runs with the
+// context of the caller that made it; callers sharing it make the call again with their
+// own context if it failed because the context of that caller was done
+func (s *store) get(ctx context.Context, key cacheKey, fetch func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	s.mu.Lock()
+	var stale *entry
+	if element, ok := s.entries[key.key]; ok {
+		cached := element.Value.(*entry)
+		if time.Now().Before(cached.expires) {
+			s.lru.MoveToFront(element)
+			s.mu.Unlock()
+			return cached.value, nil
+		}
+		stale = cached
+	}
+
+	generation := s.generation
+	callKey := key.key + "\x00" + strconv.FormatUint(generation, 10)
+	if inFlight, ok := s.calls[callKey]; ok {
+		s.mu.Unlock()
+		select {
+		case <-inFlight.done:
+			if inFlight.err != nil && inFlight.cancelled && ctx.Err() == nil {
+				return s.get(ctx, key, fetch)
+			}
+			return inFlight.value, inFlight.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	c := &call{done: make(chan struct{})}
+	s.calls[callKey] = c
+	s.mu.Unlock()
+
+	validator := &api.Validator{}
+	if stale != nil {
+		validator.IfNoneMatch = stale.etag
+	}
+	c.value, c.err = fetch(api.WithValidator(ctx, validator))
+	c.cancelled = ctx.Err() != nil
+	if errors.Is(c.err, api.ErrNotModified) && stale != nil {
+		c.value, c.err = stale.value, nil
+		if validator.ETag == "" {
+			validator.ETag = stale.etag
+		}
+	}
+
+	s.mu.Lock()
+	delete(s.calls, callKey)
+	if c.err == nil && s.generation == generation {
+		s.put(&entry{key: key, value: c.value, etag: validator.ETag, expires: time.Now().Add(s.ttl)})
+	}
+	s.mu.Unlock()
+	close(c.done)
+
+	return c.value, c.err
+}
+
+// put adds or replaces an entry and evicts the least recently used entries over the limit
+func (s *store) put(e *entry) {
+	if element, ok := s.entries[e.key.key]; ok {
+		element.Value = e
+		s.lru.MoveToFront(element)
+		return
+	}
+	s.entries[e.key.key] = s.lru.PushFront(e)
+	for s.lru.Len() > s.maxEntries {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*entry).key.key)
+	}
+}
+
+// invalidate drops the cached responses of the cluster
+func (s *store) invalidate(kubeContext string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	for key, element := range s.entries {
+		if element.Value.(*entry).key.kubeContext == kubeContext {
+			s.lru.Remove(element)
+			delete(s.entries, key)
+		}
+	}
+}
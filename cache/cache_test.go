@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/gojekfarm/albatross-client-go/config"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer serves the status of any release with an etag, and counts the requests
+type testServer struct {
+	*httptest.Server
+	requests    int32
+	notModified int32
+	delay       time.Duration
+}
+
+func newTestServer(t *testing.T) *testServer {
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		time.Sleep(s.delay)
+		if r.Method == http.MethodPut {
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "deployed"})
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&s.notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		parts := strings.Split(r.URL.Path, "/")
+		_ = json.NewEncoder(w).Encode(release.Release{Name: parts[len(parts)-1], Status: "deployed"})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newCachedClient(t *testing.T, server *testServer, opts ...Option) api.Client {
+	client, err := api.NewClient(server.URL, config.WithKubeContext("staging"))
+	require.NoError(t, err)
+	return Wrap(client, opts...)
+}
+
+func TestCachedClientServesResponsesWithinTheTTL(t *testing.T) {
+	server := newTestServer(t)
+	client := newCachedClient(t, server, WithTTL(time.Minute))
+
+	for i := 0; i < 3; i++ {
+		rel, err := client.Status(context.Background(), "api", flags.StatusFlags{})
+		require.NoError(t, err)
+		assert.Equal(t, "api", rel.Name)
+	}
+	_, err := client.Status(context.Background(), "api", flags.StatusFlags{Revision: 2})
+	require.NoError(t, err)
+	_, err = client.ForCluster("production").Status(context.Background(), "api", flags.StatusFlags{})
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&server.requests))
+}
+
+func TestCachedClientRevalidatesExpiredResponses(t *testing.T) {
+	server := newTestServer(t)
+	client := newCachedClient(t, server, WithTTL(time.Nanosecond))
+
+	for i := 0; i < 3; i++ {
+		rel, err := client.Status(context.Background(), "api", flags.StatusFlags{})
+		require.NoError(t, err)
+		assert.Equal(t, "deployed", rel.Status)
+	}
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&server.requests))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.notModified))
+}
+
+func TestCachedClientSharesConcurrentIdenticalRequests(t *testing.T) {
+	server := newTestServer(t)
+	server.delay = 50 * time.Millisecond
+	client := newCachedClient(t, server)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rel, err := client.Status(context.Background(), "api", flags.StatusFlags{})
+			assert.NoError(t, err)
+			assert.Equal(t, "api", rel.Name)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.requests))
+}
+
+func TestCachedClientRetriesSharedRequestsWhenTheFirstCallerCancels(t *testing.T) {
+	server := newTestServer(t)
+	server.delay = 50 * time.Millisecond
+	client := newCachedClient(t, server)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := client.Status(ctx, "api", flags.StatusFlags{})
+		first <- err
+	}()
+	// The second request shares the call of the first one, which is cancelled while in flight
+	time.Sleep(10 * time.Millisecond)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	rel, err := client.Status(context.Background(), "api", flags.StatusFlags{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "api", rel.Name)
+	assert.Error(t, <-first)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.requests))
+}
+
+func TestCachedClientInvalidatesTheClusterOnMutatingCalls(t *testing.T) {
+	server := newTestServer(t)
+	client := newCachedClient(t, server, WithTTL(time.Minute))
+	production := client.ForCluster("production")
+
+	_, err := client.Status(context.Background(), "api", flags.StatusFlags{})
+	require.NoError(t, err)
+	_, err = production.Status(context.Background(), "api", flags.StatusFlags{})
+	require.NoError(t, err)
+	_, err = client.Upgrade(context.Background(), "api", "stable/api", nil, flags.UpgradeFlags{})
+	require.NoError(t, err)
+	_, err = client.Status(context.Background(), "api", flags.StatusFlags{})
+	require.NoError(t, err)
+	_, err = production.Status(context.Background(), "api", flags.StatusFlags{})
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(4), atomic.LoadInt32(&server.requests), "only the status of the upgraded cluster is fetched again")
+}
+
+func TestCachedClientEvictsLeastRecentlyUsedResponses(t *testing.T) {
+	server := newTestServer(t)
+	client := newCachedClient(t, server, WithTTL(time.Minute), WithMaxEntries(2))
+
+	for _, name := range []string{"api", "worker", "api", "web", "api", "worker"} {
+		_, err := client.Status(context.Background(), name, flags.StatusFlags{})
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(4), atomic.LoadInt32(&server.requests))
+}
+
+func TestCachedClientDoesNotShareResponsesAcrossCredentials(t *testing.T) {
+	server := newTestServer(t)
+	httpClient, err := api.NewClient(server.URL, config.WithKubeContext("staging"), config.WithKubeCredentialsInHeaders())
+	require.NoError(t, err)
+	client := Wrap(httpClient, WithTTL(time.Minute))
+
+	for _, token := range []flags.Secret{"alice", "bob", "alice"} {
+		_, err := client.Status(context.Background(), "api", flags.StatusFlags{CommonFlags: flags.CommonFlags{KubeToken: token}})
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.requests))
+}
@@ -0,0 +1,100 @@
+package chart
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Chart represents a version of a helm chart available in a chart repository.
+// All apis that return a chart should return an instance of this struct
+type Chart struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	AppVersion  string `json:"app_version"`
+	Description string `json:"description"`
+	Deprecated  bool   `json:"deprecated"`
+}
+
+// Filter returns the charts whose version satisfies the semver constraint, such as "~1.2"
+// or ">=1.4 <2", sorted from the highest to the lowest version. Charts whose version is
+// not valid semver are left out
+func Filter(charts []Chart, constraint string) ([]Chart, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint %q: %s", constraint, err)
+	}
+
+	type versioned struct {
+		chart   Chart
+		version *semver.Version
+	}
+	var matched []versioned
+	for _, ch := range charts {
+		v, err := semver.NewVersion(ch.Version)
+		if err != nil || !c.Check(v) {
+			continue
+		}
+		matched = append(matched, versioned{ch, v})
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].version.GreaterThan(matched[j].version)
+	})
+
+	filtered := make([]Chart, len(matched))
+	for i, m := range matched {
+		filtered[i] = m.chart
+	}
+	return filtered, nil
+}
+
+// Sort sorts charts from the highest to the lowest version, including pre-releases.
+// Charts whose version is not valid semver are moved to the end
+func Sort(charts []Chart) {
+	versions := make(map[string]*semver.Version, len(charts))
+	for _, ch := range charts {
+		if v, err := semver.NewVersion(ch.Version); err == nil {
+			versions[ch.Version] = v
+		}
+	}
+
+	sort.SliceStable(charts, func(i, j int) bool {
+		vi, vj := versions[charts[i].Version], versions[charts[j].Version]
+		if vi == nil || vj == nil {
+			return vj == nil && vi != nil
+		}
+		return vi.GreaterThan(vj)
+	})
+}
+
+// Latest returns the chart with the highest version that satisfies the constraint
+func Latest(charts []Chart, constraint string) (Chart, error) {
+	filtered, err := Filter(charts, constraint)
+	if err != nil {
+		return Chart{}, err
+	}
+	if len(filtered) == 0 {
+		return Chart{}, fmt.Errorf("no chart version satisfies the constraint %q", constraint)
+	}
+	return filtered[0], nil
+}
+
+// SplitNameVersion splits the chart of a release, which helm reports as <name>-<version>,
+// such as "api-gateway-1.2.0-rc.1", on the last '-' that starts a valid semver version.
+// Helm also accepts versions such as "1.2" or "v1.2.3", which are only considered when
+// no '-' starts a strict semver version, so that "api-1.2.0-1" is not split before "1".
+// It reports false if there is no such '-'
+func SplitNameVersion(releaseChart string) (name string, version string, ok bool) {
+	parsers := []func(string) (*semver.Version, error){semver.StrictNewVersion, semver.NewVersion}
+	for _, parse := range parsers {
+		for i := strings.LastIndexByte(releaseChart, '-'); i > 0; i = strings.LastIndexByte(releaseChart[:i], '-') {
+			if _, err := parse(releaseChart[i+1:]); err == nil {
+				return releaseChart[:i], releaseChart[i+1:], true
+			}
+		}
+	}
+	return releaseChart, "", false
+}
@@ -0,0 +1,83 @@
+package chart
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCharts = []Chart{
+	{Name: "stable/nginx", Version: "1.2.0"},
+	{Name: "stable/nginx", Version: "1.3.1"},
+	{Name: "stable/nginx", Version: "1.2.5"},
+	{Name: "stable/nginx", Version: "2.0.0-rc.1"},
+	{Name: "stable/nginx", Version: "latest"},
+	{Name: "stable/nginx", Version: "1.10.0"},
+}
+
+func versions(charts []Chart) []string {
+	var v []string
+	for _, ch := range charts {
+		v = append(v, ch.Version)
+	}
+	return v
+}
+
+func TestFilter(t *testing.T) {
+	filtered, err := Filter(testCharts, "~1.2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2.5", "1.2.0"}, versions(filtered))
+
+	filtered, err = Filter(testCharts, ">=1.3 <2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.10.0", "1.3.1"}, versions(filtered))
+
+	_, err = Filter(testCharts, "~>>1")
+	assert.Error(t, err)
+}
+
+func TestLatest(t *testing.T) {
+	latest, err := Latest(testCharts, "^1.2")
+	require.NoError(t, err)
+	assert.Equal(t, "1.10.0", latest.Version)
+
+	_, err = Latest(testCharts, ">=3")
+	assert.EqualError(t, err, `no chart version satisfies the constraint ">=3"`)
+}
+
+func TestSort(t *testing.T) {
+	charts := append([]Chart{}, testCharts...)
+	Sort(charts)
+	assert.Equal(t, []string{"2.0.0-rc.1", "1.10.0", "1.3.1", "1.2.5", "1.2.0", "latest"}, versions(charts))
+}
+
+func TestSplitNameVersion(t *testing.T) {
+	testCases := []struct {
+		releaseChart string
+		name         string
+		version      string
+		ok           bool
+	}{
+		{"api-1.2.0", "api", "1.2.0", true},
+		{"api-gateway-1.2.0", "api-gateway", "1.2.0", true},
+		{"api-1.2.0-rc.1", "api", "1.2.0-rc.1", true},
+		{"api-1.2.0-1", "api", "1.2.0-1", true},
+		{"api-v2-1.0.0+build.5", "api-v2", "1.0.0+build.5", true},
+		{"api-1.2", "api", "1.2", true},
+		{"api-gateway-1.2", "api-gateway", "1.2", true},
+		{"api-v1.2.3", "api", "v1.2.3", true},
+		{"api-v2-1.2", "api-v2", "1.2", true},
+		{"api-1.2-rc.1", "api", "1.2-rc.1", true},
+		{"api-latest", "api-latest", "", false},
+		{"api", "api", "", false},
+	}
+
+	for _, tc := range testCases {
+		name, version, ok := SplitNameVersion(tc.releaseChart)
+
+		assert.Equal(t, tc.name, name, tc.releaseChart)
+		assert.Equal(t, tc.version, version, tc.releaseChart)
+		assert.Equal(t, tc.ok, ok, tc.releaseChart)
+	}
+}
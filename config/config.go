@@ -1,8 +1,15 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
 	"time"
 
+	"github.com/gojekfarm/albatross-client-go/flags"
 	"github.com/gojekfarm/albatross-client-go/logger"
 )
 
@@ -19,8 +26,116 @@ type Retry struct {
 	Backoff time.Duration
 }
 
+// Auth keeps the credentials used to authenticate against the albatross api server.
+// Either a bearer token or a username/password pair can be set, but not both
+type Auth struct {
+	Token    flags.Secret
+	Username string
+	Password flags.Secret
+}
+
+// TLS keeps the tls settings used to connect to the albatross api server
+type TLS struct {
+	// CAFile is the path to a PEM encoded CA bundle used to verify the server
+	CAFile string
+
+	// CertFile and KeyFile are paths to a PEM encoded client certificate and key
+	CertFile string
+	KeyFile  string
+
+	InsecureSkipVerify bool
+}
+
+// Strategy determines how requests are spread over multiple hosts
+type Strategy string
+
+const (
+	// RoundRobin spreads requests over all healthy hosts in turn
+	RoundRobin Strategy = "round_robin"
+
+	// Failover sends requests to the first healthy host in the order they are configured
+	Failover Strategy = "failover"
+)
+
+// HostPolicy keeps the load balancing policy for clients with multiple hosts
+type HostPolicy struct {
+	Strategy Strategy
+
+	// ReprobeInterval is the time a host is skipped after it is marked unhealthy.
+	// Once it has passed, the host receives requests again and is marked healthy
+	// on the first successful response
+	ReprobeInterval time.Duration
+}
+
+// DefaultHostPolicy returns the host policy used when multiple hosts are
+// configured without a policy
+func DefaultHostPolicy() *HostPolicy {
+	return &HostPolicy{
+		Strategy:        RoundRobin,
+		ReprobeInterval: 30 * time.Second,
+	}
+}
+
+// RateLimit keeps the client side limits on requests sent to the albatross api server
+type RateLimit struct {
+	// RequestsPerSecond is the rate of the token bucket. Every request takes one token,
+	// and waits for it if the bucket is empty. Zero disables rate limiting
+	RequestsPerSecond float64
+
+	// Burst is the size of the token bucket. Defaults to 1
+	Burst int
+
+	// MaxInFlight is the maximum number of concurrent requests. Zero means no limit
+	MaxInFlight int
+
+	// PerKubeContext keeps separate limits for every kube context instead of one for the client
+	PerKubeContext bool
+}
+
+// CircuitState is the state of the circuit breaker of a host
+type CircuitState string
+
+const (
+	// CircuitClosed lets all requests through
+	CircuitClosed CircuitState = "closed"
+
+	// CircuitOpen rejects all requests until the cool down has passed
+	CircuitOpen CircuitState = "open"
+
+	// CircuitHalfOpen lets a limited number of trial requests through
+	// to decide whether the circuit should close again
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreaker keeps the circuit breaker policy, which is applied to each host separately
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit.
+	// Network errors and 5xx responses count as failures
+	FailureThreshold int
+
+	// CoolDown is the time the circuit stays open before trial requests are let through
+	CoolDown time.Duration
+
+	// HalfOpenRequests is the number of concurrent trial requests allowed while
+	// the circuit is half open. Defaults to 1
+	HalfOpenRequests int
+
+	// OnStateChange is called whenever the circuit of a host changes state,
+	// e.g. to record metrics. State changes are logged regardless
+	OnStateChange func(host string, from CircuitState, to CircuitState)
+}
+
 // Config defines settings for a new client
 type Config struct {
+	// Host is the base url of the albatross api server
+	Host string
+
+	// Hosts are the base urls of additional replicas of the albatross api server
+	Hosts []string
+
+	// HostPolicy governs how requests are spread over Host and Hosts
+	HostPolicy *HostPolicy
+
 	// Timeout for API calls
 	Timeout time.Duration
 
@@ -29,6 +144,37 @@ type Config struct {
 
 	// The logger instance for the client
 	Logger logger.Logger
+
+	// RateLimit is disabled when nil
+	RateLimit *RateLimit
+
+	// CircuitBreaker is disabled when nil
+	CircuitBreaker *CircuitBreaker
+
+	// Auth credentials sent with every request
+	Auth *Auth
+
+	// TLS settings for the underlying transport
+	TLS *TLS
+
+	// KubeContext and Namespace are the default cluster and namespace of the client,
+	// used by the apis when the flags leave them empty
+	KubeContext string
+	Namespace   string
+
+	// KubeToken and KubeAPIServer are the default credentials for the cluster. They are
+	// only used for requests to the default cluster
+	KubeToken     flags.Secret
+	KubeAPIServer string
+
+	// KubeCredentialsInHeaders sends the kube token and api server in the X-Kube-Token and
+	// X-Kube-Api-Server headers of every request instead of the flags of install, upgrade,
+	// rollback and test request bodies. The albatross server must read them from the headers
+	KubeCredentialsInHeaders bool
+
+	// ValidateValues fetches the values schema of the chart and validates the values
+	// against it before install and upgrade requests are sent
+	ValidateValues bool
 }
 
 // DefaultConfig returns a default Config struct with sensible defaults set
@@ -39,6 +185,115 @@ func DefaultConfig() *Config {
 	}
 }
 
+// HostList returns Host followed by the additional Hosts, without duplicates
+func (c *Config) HostList() []string {
+	var hosts []string
+	seen := map[string]bool{}
+	for _, host := range append([]string{c.Host}, c.Hosts...) {
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// Validate checks the config for invalid or conflicting settings
+func (c *Config) Validate() error {
+	hosts := c.HostList()
+	if len(hosts) == 0 {
+		return errors.New("host is a required parameter")
+	}
+	for _, host := range hosts {
+		if _, err := url.ParseRequestURI(host); err != nil {
+			return fmt.Errorf("invalid host %q: %s", host, err)
+		}
+	}
+	if c.HostPolicy != nil {
+		if c.HostPolicy.Strategy != RoundRobin && c.HostPolicy.Strategy != Failover {
+			return fmt.Errorf("invalid host strategy: %q", c.HostPolicy.Strategy)
+		}
+		if c.HostPolicy.ReprobeInterval < 0 {
+			return errors.New("host reprobe interval cannot be negative")
+		}
+	}
+	if c.Timeout < 0 {
+		return errors.New("timeout cannot be negative")
+	}
+	if c.Retry != nil {
+		if c.Retry.RetryCount < 0 {
+			return errors.New("retry count cannot be negative")
+		}
+		if c.Retry.Backoff < 0 {
+			return errors.New("retry backoff cannot be negative")
+		}
+	}
+	if c.RateLimit != nil {
+		if c.RateLimit.RequestsPerSecond < 0 {
+			return errors.New("rate limit requests per second cannot be negative")
+		}
+		if c.RateLimit.Burst < 0 {
+			return errors.New("rate limit burst cannot be negative")
+		}
+		if c.RateLimit.MaxInFlight < 0 {
+			return errors.New("rate limit max in flight cannot be negative")
+		}
+	}
+	if c.CircuitBreaker != nil {
+		if c.CircuitBreaker.FailureThreshold <= 0 {
+			return errors.New("circuit breaker failure threshold must be positive")
+		}
+		if c.CircuitBreaker.CoolDown <= 0 {
+			return errors.New("circuit breaker cool down must be positive")
+		}
+		if c.CircuitBreaker.HalfOpenRequests < 0 {
+			return errors.New("circuit breaker half open requests cannot be negative")
+		}
+	}
+	if c.Auth != nil {
+		if c.Auth.Token != "" && (c.Auth.Username != "" || c.Auth.Password != "") {
+			return errors.New("auth token and username/password cannot be used together")
+		}
+		if c.Auth.Password != "" && c.Auth.Username == "" {
+			return errors.New("auth password requires a username")
+		}
+	}
+	if c.TLS != nil && (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return errors.New("tls cert file and key file must be set together")
+	}
+	return nil
+}
+
+// ClientConfig builds a tls.Config by loading the configured certificate files
+func (t *TLS) ClientConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: t.InsecureSkipVerify, //nolint:gosec
+	}
+
+	if t.CAFile != "" {
+		caCert, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading the tls ca file: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in tls ca file: %s", t.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading the tls client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
 // WithRetry allows the user to set a custom timeout for api calls
 func WithTimeout(timeout time.Duration) Option {
 	return func(config *Config) {
@@ -59,3 +314,87 @@ func WithLogger(logger logger.Logger) Option {
 		config.Logger = logger
 	}
 }
+
+// WithRateLimit sets the client side rate and concurrency limits
+func WithRateLimit(rateLimit *RateLimit) Option {
+	return func(config *Config) {
+		config.RateLimit = rateLimit
+	}
+}
+
+// WithCircuitBreaker enables the circuit breaker for each host
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(config *Config) {
+		config.CircuitBreaker = breaker
+	}
+}
+
+// WithHosts adds replicas of the albatross api server that requests can be sent to
+func WithHosts(hosts ...string) Option {
+	return func(config *Config) {
+		config.Hosts = append(config.Hosts, hosts...)
+	}
+}
+
+// WithHostPolicy sets the load balancing policy for multiple hosts
+func WithHostPolicy(policy *HostPolicy) Option {
+	return func(config *Config) {
+		config.HostPolicy = policy
+	}
+}
+
+// WithAuth sets the credentials sent to the albatross api server
+func WithAuth(auth *Auth) Option {
+	return func(config *Config) {
+		config.Auth = auth
+	}
+}
+
+// WithTLS sets the tls settings for the client
+func WithTLS(tlsConfig *TLS) Option {
+	return func(config *Config) {
+		config.TLS = tlsConfig
+	}
+}
+
+// WithKubeContext sets the default cluster of the client
+func WithKubeContext(kubeContext string) Option {
+	return func(config *Config) {
+		config.KubeContext = kubeContext
+	}
+}
+
+// WithNamespace sets the default namespace of the client
+func WithNamespace(namespace string) Option {
+	return func(config *Config) {
+		config.Namespace = namespace
+	}
+}
+
+// WithKubeToken sets the default token used to access the default cluster
+func WithKubeToken(token string) Option {
+	return func(config *Config) {
+		config.KubeToken = flags.Secret(token)
+	}
+}
+
+// WithKubeAPIServer sets the default api server address of the default cluster
+func WithKubeAPIServer(apiServer string) Option {
+	return func(config *Config) {
+		config.KubeAPIServer = apiServer
+	}
+}
+
+// WithKubeCredentialsInHeaders sends the kube credentials in request headers instead of bodies
+func WithKubeCredentialsInHeaders() Option {
+	return func(config *Config) {
+		config.KubeCredentialsInHeaders = true
+	}
+}
+
+// WithValuesValidation validates values against the schema of the chart before install and upgrade
+func WithValuesValidation() Option {
+	return func(config *Config) {
+		config.ValidateValues = true
+	}
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
@@ -25,3 +27,63 @@ func TestConfigWithRetry(t *testing.T) {
 	assert.Equal(t, config.Retry.RetryCount, retry.RetryCount)
 	assert.Equal(t, config.Retry.Backoff, retry.Backoff)
 }
+
+func TestConfigRedactsCredentials(t *testing.T) {
+	config := DefaultConfig()
+	WithKubeToken("kube-token")(config)
+	WithAuth(&Auth{Username: "user", Password: "hunter2"})(config)
+
+	data, err := json.Marshal(config.Auth)
+	assert.NoError(t, err)
+	for _, out := range []string{fmt.Sprintf("%v %+v", config.KubeToken, *config.Auth), string(data)} {
+		assert.NotContains(t, out, "kube-token")
+		assert.NotContains(t, out, "hunter2")
+		assert.Contains(t, out, "[REDACTED]")
+	}
+	assert.Equal(t, "hunter2", config.Auth.Password.Reveal())
+}
+
+func TestConfigValidate(t *testing.T) {
+	validConfig := func() *Config {
+		config := DefaultConfig()
+		config.Host = "http://localhost:8080"
+		return config
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(*Config)
+		err    string
+	}{
+		{"valid config", func(c *Config) {}, ""},
+		{"invalid host", func(c *Config) { c.Host = "localhost" }, `invalid host "localhost": parse "localhost": invalid URI for request`},
+		{"negative timeout", func(c *Config) { c.Timeout = -time.Second }, "timeout cannot be negative"},
+		{"negative retry count", func(c *Config) { c.Retry = &Retry{RetryCount: -1} }, "retry count cannot be negative"},
+		{"password without username", func(c *Config) { c.Auth = &Auth{Password: "secret"} }, "auth password requires a username"},
+		{"invalid additional host", func(c *Config) { c.Hosts = []string{"replica"} }, `invalid host "replica": parse "replica": invalid URI for request`},
+		{"invalid host strategy", func(c *Config) { c.HostPolicy = &HostPolicy{Strategy: "random"} }, `invalid host strategy: "random"`},
+		{"circuit breaker without threshold", func(c *Config) { c.CircuitBreaker = &CircuitBreaker{CoolDown: time.Second} }, "circuit breaker failure threshold must be positive"},
+		{"cert without key", func(c *Config) { c.TLS = &TLS{CertFile: "cert.pem"} }, "tls cert file and key file must be set together"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := validConfig()
+			tc.modify(config)
+			err := config.Validate()
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestConfigHostList(t *testing.T) {
+	config := DefaultConfig()
+	config.Host = "http://a"
+	WithHosts("http://b", "http://a", "http://c")(config)
+
+	assert.Equal(t, []string{"http://a", "http://b", "http://c"}, config.HostList())
+}
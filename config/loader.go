@@ -0,0 +1,345 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables read by Load. They take precedence over the config file.
+const (
+	EnvConfigFile    = "ALBATROSS_CONFIG"
+	EnvProfile       = "ALBATROSS_PROFILE"
+	EnvHost          = "ALBATROSS_HOST"
+	EnvHosts         = "ALBATROSS_HOSTS"
+	EnvHostStrategy  = "ALBATROSS_HOST_STRATEGY"
+	EnvTimeout       = "ALBATROSS_TIMEOUT"
+	EnvRetryCount    = "ALBATROSS_RETRY_COUNT"
+	EnvRetryBackoff  = "ALBATROSS_RETRY_BACKOFF"
+	EnvToken         = "ALBATROSS_TOKEN"
+	EnvUsername      = "ALBATROSS_USERNAME"
+	EnvPassword      = "ALBATROSS_PASSWORD"
+	EnvTLSCAFile     = "ALBATROSS_TLS_CA_FILE"
+	EnvTLSCertFile   = "ALBATROSS_TLS_CERT_FILE"
+	EnvTLSKeyFile    = "ALBATROSS_TLS_KEY_FILE"
+	EnvTLSInsecure   = "ALBATROSS_TLS_INSECURE"
+	EnvKubeContext   = "ALBATROSS_KUBE_CONTEXT"
+	EnvNamespace     = "ALBATROSS_NAMESPACE"
+	EnvKubeToken     = "ALBATROSS_KUBE_TOKEN"
+	EnvKubeAPIServer = "ALBATROSS_KUBE_APISERVER"
+)
+
+const defaultProfile = "default"
+
+// File is the yaml schema of the client config file. It holds
+// one named profile per environment
+type File struct {
+	CurrentProfile string             `yaml:"current_profile"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+}
+
+// Profile is the yaml schema of a single profile in the config file
+type Profile struct {
+	Host          string            `yaml:"host"`
+	Hosts         []string          `yaml:"hosts"`
+	HostPolicy    *HostProfile      `yaml:"host_policy"`
+	Timeout       time.Duration     `yaml:"timeout"`
+	Retry         *RetryProfile     `yaml:"retry"`
+	RateLimit     *RateLimitProfile `yaml:"rate_limit"`
+	Breaker       *BreakerProfile   `yaml:"circuit_breaker"`
+	Auth          *AuthProfile      `yaml:"auth"`
+	TLS           *TLSProfile       `yaml:"tls"`
+	KubeContext   string            `yaml:"kube_context"`
+	Namespace     string            `yaml:"namespace"`
+	KubeToken     string            `yaml:"kube_token"`
+	KubeAPIServer string            `yaml:"kube_apiserver"`
+
+	KubeCredentialsInHeaders bool `yaml:"kube_credentials_in_headers"`
+	ValidateValues           bool `yaml:"validate_values"`
+}
+
+// RetryProfile is the yaml schema of the retry policy
+type RetryProfile struct {
+	Count   int           `yaml:"count"`
+	Backoff time.Duration `yaml:"backoff"`
+}
+
+// HostProfile is the yaml schema of the host policy
+type HostProfile struct {
+	Strategy        Strategy      `yaml:"strategy"`
+	ReprobeInterval time.Duration `yaml:"reprobe_interval"`
+}
+
+// RateLimitProfile is the yaml schema of the rate limits
+type RateLimitProfile struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+	MaxInFlight       int     `yaml:"max_in_flight"`
+	PerKubeContext    bool    `yaml:"per_kube_context"`
+}
+
+// BreakerProfile is the yaml schema of the circuit breaker policy
+type BreakerProfile struct {
+	FailureThreshold int           `yaml:"failure_threshold"`
+	CoolDown         time.Duration `yaml:"cool_down"`
+	HalfOpenRequests int           `yaml:"half_open_requests"`
+}
+
+// AuthProfile is the yaml schema of the auth credentials
+type AuthProfile struct {
+	Token    string `yaml:"token"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// TLSProfile is the yaml schema of the tls settings
+type TLSProfile struct {
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// Load builds a Config from the defaults, the given profile of the config file
+// and the ALBATROSS_* environment variables, in increasing order of precedence.
+// If path is empty, ALBATROSS_CONFIG is used and the file is skipped when neither is set.
+// If profile is empty, ALBATROSS_PROFILE is used, falling back to the current_profile
+// of the file and then to "default".
+// The resulting config is validated before it is returned
+func Load(path string, profile string) (*Config, error) {
+	return load(path, profile, os.LookupEnv)
+}
+
+func load(path string, profile string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := DefaultConfig()
+
+	if path == "" {
+		path, _ = lookupEnv(EnvConfigFile)
+	}
+	if profile == "" {
+		profile, _ = lookupEnv(EnvProfile)
+	}
+
+	if path != "" {
+		file, err := ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if profile == "" {
+			profile = file.CurrentProfile
+		}
+		if profile == "" {
+			profile = defaultProfile
+		}
+		p, ok := file.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("profile %q not found in config file %s", profile, path)
+		}
+		p.apply(cfg)
+	}
+
+	if err := applyEnv(cfg, lookupEnv); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ReadFile reads and parses a client config file
+func ReadFile(path string) (*File, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the config file: %s", err)
+	}
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("Error parsing the config file %s: %s", path, err)
+	}
+	return &file, nil
+}
+
+func (p Profile) apply(cfg *Config) {
+	if p.Host != "" {
+		cfg.Host = p.Host
+	}
+	if len(p.Hosts) > 0 {
+		cfg.Hosts = p.Hosts
+	}
+	if p.HostPolicy != nil {
+		cfg.HostPolicy = DefaultHostPolicy()
+		if p.HostPolicy.Strategy != "" {
+			cfg.HostPolicy.Strategy = p.HostPolicy.Strategy
+		}
+		if p.HostPolicy.ReprobeInterval != 0 {
+			cfg.HostPolicy.ReprobeInterval = p.HostPolicy.ReprobeInterval
+		}
+	}
+	if p.Timeout != 0 {
+		cfg.Timeout = p.Timeout
+	}
+	if p.Retry != nil {
+		cfg.Retry = &Retry{RetryCount: p.Retry.Count, Backoff: p.Retry.Backoff}
+	}
+	if p.RateLimit != nil {
+		cfg.RateLimit = &RateLimit{
+			RequestsPerSecond: p.RateLimit.RequestsPerSecond,
+			Burst:             p.RateLimit.Burst,
+			MaxInFlight:       p.RateLimit.MaxInFlight,
+			PerKubeContext:    p.RateLimit.PerKubeContext,
+		}
+	}
+	if p.Breaker != nil {
+		cfg.CircuitBreaker = &CircuitBreaker{
+			FailureThreshold: p.Breaker.FailureThreshold,
+			CoolDown:         p.Breaker.CoolDown,
+			HalfOpenRequests: p.Breaker.HalfOpenRequests,
+		}
+	}
+	if p.Auth != nil {
+		cfg.Auth = &Auth{Token: flags.Secret(p.Auth.Token), Username: p.Auth.Username, Password: flags.Secret(p.Auth.Password)}
+	}
+	if p.TLS != nil {
+		cfg.TLS = &TLS{
+			CAFile:             p.TLS.CAFile,
+			CertFile:           p.TLS.CertFile,
+			KeyFile:            p.TLS.KeyFile,
+			InsecureSkipVerify: p.TLS.InsecureSkipVerify,
+		}
+	}
+	if p.KubeContext != "" {
+		cfg.KubeContext = p.KubeContext
+	}
+	if p.Namespace != "" {
+		cfg.Namespace = p.Namespace
+	}
+	if p.KubeToken != "" {
+		cfg.KubeToken = flags.Secret(p.KubeToken)
+	}
+	if p.KubeAPIServer != "" {
+		cfg.KubeAPIServer = p.KubeAPIServer
+	}
+	if p.KubeCredentialsInHeaders {
+		cfg.KubeCredentialsInHeaders = true
+	}
+	if p.ValidateValues {
+		cfg.ValidateValues = true
+	}
+}
+
+func applyEnv(cfg *Config, lookupEnv func(string) (string, bool)) error {
+	if v, ok := lookupEnv(EnvHost); ok {
+		cfg.Host = v
+	}
+	if v, ok := lookupEnv(EnvHosts); ok {
+		cfg.Hosts = nil
+		for _, host := range strings.Split(v, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				cfg.Hosts = append(cfg.Hosts, host)
+			}
+		}
+	}
+	if v, ok := lookupEnv(EnvHostStrategy); ok {
+		if cfg.HostPolicy == nil {
+			cfg.HostPolicy = DefaultHostPolicy()
+		}
+		cfg.HostPolicy.Strategy = Strategy(v)
+	}
+	if v, ok := lookupEnv(EnvTimeout); ok {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return invalidEnv(EnvTimeout, err)
+		}
+		cfg.Timeout = timeout
+	}
+
+	if v, ok := lookupEnv(EnvRetryCount); ok {
+		count, err := strconv.Atoi(v)
+		if err != nil {
+			return invalidEnv(EnvRetryCount, err)
+		}
+		retry(cfg).RetryCount = count
+	}
+	if v, ok := lookupEnv(EnvRetryBackoff); ok {
+		backoff, err := time.ParseDuration(v)
+		if err != nil {
+			return invalidEnv(EnvRetryBackoff, err)
+		}
+		retry(cfg).Backoff = backoff
+	}
+
+	if v, ok := lookupEnv(EnvToken); ok {
+		auth(cfg).Token = flags.Secret(v)
+	}
+	if v, ok := lookupEnv(EnvUsername); ok {
+		auth(cfg).Username = v
+	}
+	if v, ok := lookupEnv(EnvPassword); ok {
+		auth(cfg).Password = flags.Secret(v)
+	}
+
+	if v, ok := lookupEnv(EnvTLSCAFile); ok {
+		tlsSettings(cfg).CAFile = v
+	}
+	if v, ok := lookupEnv(EnvTLSCertFile); ok {
+		tlsSettings(cfg).CertFile = v
+	}
+	if v, ok := lookupEnv(EnvTLSKeyFile); ok {
+		tlsSettings(cfg).KeyFile = v
+	}
+	if v, ok := lookupEnv(EnvTLSInsecure); ok {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			return invalidEnv(EnvTLSInsecure, err)
+		}
+		tlsSettings(cfg).InsecureSkipVerify = insecure
+	}
+
+	if v, ok := lookupEnv(EnvKubeContext); ok {
+		cfg.KubeContext = v
+	}
+	if v, ok := lookupEnv(EnvNamespace); ok {
+		cfg.Namespace = v
+	}
+	if v, ok := lookupEnv(EnvKubeToken); ok {
+		cfg.KubeToken = flags.Secret(v)
+	}
+	if v, ok := lookupEnv(EnvKubeAPIServer); ok {
+		cfg.KubeAPIServer = v
+	}
+	return nil
+}
+
+func invalidEnv(name string, err error) error {
+	return fmt.Errorf("invalid value for %s: %s", name, err)
+}
+
+// retry, auth and tlsSettings return the nested settings, creating them if they are not set
+func retry(cfg *Config) *Retry {
+	if cfg.Retry == nil {
+		cfg.Retry = &Retry{}
+	}
+	return cfg.Retry
+}
+
+func auth(cfg *Config) *Auth {
+	if cfg.Auth == nil {
+		cfg.Auth = &Auth{}
+	}
+	return cfg.Auth
+}
+
+func tlsSettings(cfg *Config) *TLS {
+	if cfg.TLS == nil {
+		cfg.TLS = &TLS{}
+	}
+	return cfg.TLS
+}
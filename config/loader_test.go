@@ -0,0 +1,161 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigFile = `
+current_profile: staging
+profiles:
+  staging:
+    host: http://albatross.staging:8080
+    timeout: 10s
+    retry:
+      count: 3
+      backoff: 500ms
+    auth:
+      token: staging-token
+    kube_context: staging-cluster
+    namespace: apps
+    kube_token: staging-kube-token
+    kube_apiserver: https://kube.staging:6443
+  production:
+    host: https://albatross.production
+    hosts:
+      - https://albatross-replica.production
+    host_policy:
+      strategy: failover
+    tls:
+      insecure_skip_verify: true
+`
+
+func writeConfigFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "albatross-config")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestLoadReadsCurrentProfileFromFile(t *testing.T) {
+	path := writeConfigFile(t, testConfigFile)
+
+	cfg, err := load(path, "", envLookup(nil))
+
+	require.NoError(t, err)
+	assert.Equal(t, "http://albatross.staging:8080", cfg.Host)
+	assert.Equal(t, 10*time.Second, cfg.Timeout)
+	assert.Equal(t, &Retry{RetryCount: 3, Backoff: 500 * time.Millisecond}, cfg.Retry)
+	assert.Equal(t, &Auth{Token: "staging-token"}, cfg.Auth)
+	assert.Equal(t, "staging-cluster", cfg.KubeContext)
+	assert.Equal(t, "apps", cfg.Namespace)
+	assert.Equal(t, "staging-kube-token", cfg.KubeToken.Reveal())
+	assert.Equal(t, "https://kube.staging:6443", cfg.KubeAPIServer)
+	assert.NotNil(t, cfg.Logger)
+}
+
+func TestLoadReadsNamedProfile(t *testing.T) {
+	path := writeConfigFile(t, testConfigFile)
+
+	cfg, err := load(path, "production", envLookup(nil))
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://albatross.production", cfg.Host)
+	assert.Equal(t, 5*time.Second, cfg.Timeout)
+	assert.Nil(t, cfg.Retry)
+	assert.Equal(t, []string{"https://albatross-replica.production"}, cfg.Hosts)
+	assert.Equal(t, &HostPolicy{Strategy: Failover, ReprobeInterval: 30 * time.Second}, cfg.HostPolicy)
+	assert.Equal(t, &TLS{InsecureSkipVerify: true}, cfg.TLS)
+}
+
+func TestLoadEnvironmentOverridesFile(t *testing.T) {
+	path := writeConfigFile(t, testConfigFile)
+	env := map[string]string{
+		EnvConfigFile:   path,
+		EnvProfile:      "production",
+		EnvHost:         "http://localhost:8080",
+		EnvHosts:        "http://localhost:8081, http://localhost:8082",
+		EnvHostStrategy: "round_robin",
+		EnvTimeout:      "1m",
+		EnvRetryCount:   "5",
+		EnvUsername:     "user",
+		EnvPassword:     "secret",
+		EnvTLSInsecure:  "false",
+		EnvKubeContext:  "local",
+		EnvNamespace:    "kube-system",
+		EnvRetryBackoff: "2s",
+		EnvKubeToken:    "local-kube-token",
+	}
+
+	cfg, err := load("", "", envLookup(env))
+
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080", cfg.Host)
+	assert.Equal(t, []string{"http://localhost:8081", "http://localhost:8082"}, cfg.Hosts)
+	assert.Equal(t, RoundRobin, cfg.HostPolicy.Strategy)
+	assert.Equal(t, time.Minute, cfg.Timeout)
+	assert.Equal(t, &Retry{RetryCount: 5, Backoff: 2 * time.Second}, cfg.Retry)
+	assert.Equal(t, &Auth{Username: "user", Password: "secret"}, cfg.Auth)
+	assert.False(t, cfg.TLS.InsecureSkipVerify)
+	assert.Equal(t, "local", cfg.KubeContext)
+	assert.Equal(t, "kube-system", cfg.Namespace)
+	assert.Equal(t, "local-kube-token", cfg.KubeToken.Reveal())
+}
+
+func TestLoadWithoutFileUsesEnvironment(t *testing.T) {
+	cfg, err := load("", "", envLookup(map[string]string{EnvHost: "http://localhost:8080"}))
+
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080", cfg.Host)
+	assert.Equal(t, 5*time.Second, cfg.Timeout)
+}
+
+func TestLoadErrors(t *testing.T) {
+	path := writeConfigFile(t, testConfigFile)
+
+	t.Run("When the profile does not exist", func(t *testing.T) {
+		_, err := load(path, "unknown", envLookup(nil))
+		assert.EqualError(t, err, `profile "unknown" not found in config file `+path)
+	})
+
+	t.Run("When the file does not exist", func(t *testing.T) {
+		_, err := load(filepath.Join(filepath.Dir(path), "missing.yaml"), "", envLookup(nil))
+		assert.Error(t, err)
+	})
+
+	t.Run("When the file is not valid yaml", func(t *testing.T) {
+		_, err := load(writeConfigFile(t, "profiles: ["), "", envLookup(nil))
+		assert.Error(t, err)
+	})
+
+	t.Run("When an environment variable has an invalid format", func(t *testing.T) {
+		_, err := load(path, "", envLookup(map[string]string{EnvTimeout: "ten seconds"}))
+		assert.EqualError(t, err, `invalid value for ALBATROSS_TIMEOUT: time: invalid duration "ten seconds"`)
+	})
+
+	t.Run("When no host is configured", func(t *testing.T) {
+		_, err := load("", "", envLookup(nil))
+		assert.EqualError(t, err, "host is a required parameter")
+	})
+
+	t.Run("When the loaded config is invalid", func(t *testing.T) {
+		_, err := load(path, "", envLookup(map[string]string{EnvPassword: "secret"}))
+		assert.EqualError(t, err, "auth token and username/password cannot be used together")
+	})
+}
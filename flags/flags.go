@@ -1,20 +1,50 @@
 package flags
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 
-// CommonFlags are common to all apis
+	"github.com/Masterminds/semver/v3"
+)
+
+// CommonFlags are common to all apis.
+// KubeToken and KubeAPIServer are added to the flags of request bodies, or sent in request
+// headers when the client is configured to, but never in the query
 // TODO: We can maybe define setter funcs to allow setting these easily
 type CommonFlags struct {
 	KubeContext   string `json:"-" schema:"-"`
-	KubeToken     string `json:"kube_token,omitempty" schema:"-"`
-	KubeAPIServer string `json:"kube_apiserver,omitempty" schema:"-"`
+	KubeToken     Secret `json:"-" schema:"-"`
+	KubeAPIServer string `json:"-" schema:"-"`
 	Namespace     string `json:"-" schema:"-"`
 }
 
 // InstallFlags defines flags supported by the install api
 type InstallFlags struct {
-	DryRun  bool   `json:"dry_run"`
+	DryRun bool `json:"dry_run"`
+
+	// Version is either a concrete chart version or a semver constraint, such as ">=1.4 <2",
+	// which the client resolves to the highest matching version before installing
 	Version string `json:"version"`
+
+	// Wait for all resources to be ready before marking the release as successful
+	Wait bool `json:"wait,omitempty"`
+
+	// Atomic uninstalls the release if the install fails. It implies Wait
+	Atomic bool `json:"atomic,omitempty"`
+
+	// Timeout in seconds for kubernetes operations and Wait
+	Timeout int `json:"timeout,omitempty"`
+
+	CreateNamespace bool   `json:"create_namespace,omitempty"`
+	Description     string `json:"description,omitempty"`
+	DisableHooks    bool   `json:"disable_hooks,omitempty"`
+	SkipCRDs        bool   `json:"skip_crds,omitempty"`
+
+	// ValuesSchemaFile is a local values.schema.json the values are validated against
+	// before the request is sent
+	ValuesSchemaFile string `json:"-"`
 	CommonFlags
 }
 
@@ -26,14 +56,73 @@ type ListFlags struct {
 	Pending       bool `schema:"pending,omitempty"`
 	Uninstalled   bool `schema:"uninstalled,omitempty"`
 	Uninstalling  bool `schema:"uninstalling,omitempty"`
+
+	// Filter is a regular expression the release names must match
+	Filter string `schema:"filter,omitempty"`
+
+	// Selector is a kubernetes label selector for the releases, such as "team=payments,tier!=web"
+	Selector string `schema:"selector,omitempty"`
+
+	// SortBy orders the releases by name or by last updated date. Reverse inverts the order
+	SortBy  SortBy `schema:"sort_by,omitempty"`
+	Reverse bool   `schema:"reverse,omitempty"`
+
+	// Limit is the maximum number of releases returned, starting at Offset.
+	// Zero means no limit
+	Limit  int `schema:"limit,omitempty"`
+	Offset int `schema:"offset,omitempty"`
 	CommonFlags
 }
 
+// SortBy is the order of releases returned by the list api
+type SortBy string
+
+const (
+	SortByName SortBy = "name"
+	SortByDate SortBy = "date"
+)
+
 // UpgradeFlags defines flags supported by the upgrade api
 type UpgradeFlags struct {
-	DryRun  bool   `json:"dry_run"`
+	DryRun bool `json:"dry_run"`
+
+	// Version is either a concrete chart version or a semver constraint, such as ">=1.4 <2",
+	// which the client resolves to the highest matching version before upgrading
 	Version string `json:"version"`
 	Install bool   `json:"install"`
+
+	// Wait for all resources to be ready before marking the release as successful
+	Wait bool `json:"wait,omitempty"`
+
+	// Atomic rolls back the release if the upgrade fails. It implies Wait
+	Atomic bool `json:"atomic,omitempty"`
+
+	// Timeout in seconds for kubernetes operations and Wait
+	Timeout int `json:"timeout,omitempty"`
+
+	// Force resource updates through a replacement strategy
+	Force bool `json:"force,omitempty"`
+
+	// ResetValues resets the values to the ones built into the chart,
+	// ReuseValues reuses the values of the last release. They cannot be used together
+	ResetValues bool `json:"reset_values,omitempty"`
+	ReuseValues bool `json:"reuse_values,omitempty"`
+
+	// CreateNamespace creates the namespace if the release is installed. It requires Install
+	CreateNamespace bool   `json:"create_namespace,omitempty"`
+	Description     string `json:"description,omitempty"`
+	DisableHooks    bool   `json:"disable_hooks,omitempty"`
+	SkipCRDs        bool   `json:"skip_crds,omitempty"`
+
+	// MaxHistory limits the number of revisions saved per release. Zero means no limit
+	MaxHistory int `json:"max_history,omitempty"`
+
+	// CleanupOnFail deletes the resources created by the upgrade if it fails
+	CleanupOnFail bool `json:"cleanup_on_fail,omitempty"`
+
+	// ValuesSchemaFile is a local values.schema.json the values are validated against
+	// before the request is sent
+	ValuesSchemaFile string `json:"-"`
 	CommonFlags
 }
 
@@ -42,6 +131,17 @@ type StatusFlags struct {
 	CommonFlags
 }
 
+// GetValuesFlags select the values returned by the get values api
+type GetValuesFlags struct {
+	// Revision of the release, the latest one if zero
+	Revision int `schema:"revision,omitempty"`
+
+	// All returns the computed values, including the defaults of the chart,
+	// instead of the values supplied by the user
+	All bool `schema:"all,omitempty"`
+	CommonFlags
+}
+
 type UninstallFlags struct {
 	DryRun       bool `schema:"dry_run,omitempty"`
 	DisableHooks bool `schema:"disable_hooks,omitempty"`
@@ -50,58 +150,306 @@ type UninstallFlags struct {
 	CommonFlags
 }
 
-func (u *UpgradeFlags) Valid() error {
-	if u.KubeContext == "" {
-		return errors.New("kube context is a required parameter")
+// IsVersionConstraint reports whether a chart version is a semver constraint, such as
+// "~1.2", ">=1.4 <2" or "1.2.x", rather than a concrete version. Only versions with
+// constraint operators or wildcards are constraints; anything else, such as "v1.2.3"
+// or versions that are not semver, is passed to the api as it is
+func IsVersionConstraint(version string) bool {
+	if strings.ContainsAny(version, "^~<>=!,| \t") {
+		return true
 	}
-
-	if u.Namespace == "" {
-		u.Namespace = "default"
+	// Wildcards only count as whole components, so that pre-releases such as "1.0.0-fix" are versions
+	core := strings.SplitN(strings.SplitN(version, "+", 2)[0], "-", 2)[0]
+	for _, component := range strings.Split(core, ".") {
+		switch component {
+		case "x", "X", "*":
+			return true
+		}
 	}
+	return false
+}
 
+func validVersion(version string) error {
+	if !IsVersionConstraint(version) {
+		return nil
+	}
+	if _, err := semver.NewConstraint(version); err != nil {
+		return fmt.Errorf("invalid version constraint %q: %s", version, err)
+	}
 	return nil
 }
 
-func (u *InstallFlags) Valid() error {
-	if u.KubeContext == "" {
-		return errors.New("kube context is a required parameter")
+// Valid returns all problems with the flags. It does not change the flags,
+// defaults are set by WithDefaults
+func (u UpgradeFlags) Valid() error {
+	errs := u.CommonFlags.validate()
+	errs = append(errs,
+		validVersion(u.Version),
+		nonNegative("timeout", u.Timeout),
+		nonNegative("max history", u.MaxHistory),
+	)
+	if u.ResetValues && u.ReuseValues {
+		errs = append(errs, errors.New("reset values and reuse values cannot be used together"))
 	}
-
-	if u.Namespace == "" {
-		u.Namespace = "default"
+	if u.CreateNamespace && !u.Install {
+		errs = append(errs, errors.New("create namespace can only be used together with install"))
 	}
+	return Join(errs...)
+}
 
-	return nil
+// WithDefaults returns a copy of the flags with the default namespace set if it is empty
+func (u UpgradeFlags) WithDefaults() UpgradeFlags {
+	u.CommonFlags = u.CommonFlags.withDefaults()
+	return u
 }
 
-func (l *ListFlags) Valid() error {
-	if l.KubeContext == "" {
-		return errors.New("kube context is a required parameter")
+// Valid returns all problems with the flags. It does not change the flags,
+// defaults are set by WithDefaults
+func (u InstallFlags) Valid() error {
+	errs := u.CommonFlags.validate()
+	errs = append(errs,
+		validVersion(u.Version),
+		nonNegative("timeout", u.Timeout),
+	)
+	return Join(errs...)
+}
+
+// WithDefaults returns a copy of the flags with the default namespace set if it is empty
+func (u InstallFlags) WithDefaults() InstallFlags {
+	u.CommonFlags = u.CommonFlags.withDefaults()
+	return u
+}
+
+// Valid returns all problems with the flags. It does not change the flags,
+// defaults are set by WithDefaults
+func (l ListFlags) Valid() error {
+	errs := l.CommonFlags.validate()
+	if l.AllNamespaces && l.Namespace != "" {
+		errs = append(errs, errors.New("namespace cannot be used together with all namespaces"))
+	}
+	if l.Filter != "" {
+		if _, err := regexp.Compile(l.Filter); err != nil {
+			errs = append(errs, fmt.Errorf("invalid filter %q: %s", l.Filter, err))
+		}
+	}
+	switch l.SortBy {
+	case "", SortByName, SortByDate:
+	default:
+		errs = append(errs, fmt.Errorf("invalid sort %q, must be %q or %q", l.SortBy, SortByName, SortByDate))
+	}
+	errs = append(errs,
+		nonNegative("limit", l.Limit),
+		nonNegative("offset", l.Offset),
+	)
+	return Join(errs...)
+}
+
+// WithDefaults returns a copy of the flags with the default namespace set if it is
+// empty, unless the releases of all namespaces are listed
+func (l ListFlags) WithDefaults() ListFlags {
+	if !l.AllNamespaces {
+		l.CommonFlags = l.CommonFlags.withDefaults()
 	}
-	if !l.AllNamespaces && l.Namespace == "" {
-		l.Namespace = "default"
+	return l
+}
+
+// Valid returns all problems with the flags. It does not change the flags,
+// defaults are set by WithDefaults
+func (s StatusFlags) Valid() error {
+	errs := s.CommonFlags.validate()
+	errs = append(errs, nonNegative("revision", s.Revision))
+	return Join(errs...)
+}
+
+// WithDefaults returns a copy of the flags with the default namespace set if it is empty
+func (s StatusFlags) WithDefaults() StatusFlags {
+	s.CommonFlags = s.CommonFlags.withDefaults()
+	return s
+}
+
+// Valid returns all problems with the flags. It does not change the flags,
+// defaults are set by WithDefaults
+func (g GetValuesFlags) Valid() error {
+	errs := g.CommonFlags.validate()
+	errs = append(errs, nonNegative("revision", g.Revision))
+	return Join(errs...)
+}
+
+// WithDefaults returns a copy of the flags with the default namespace set if it is empty
+func (g GetValuesFlags) WithDefaults() GetValuesFlags {
+	g.CommonFlags = g.CommonFlags.withDefaults()
+	return g
+}
+
+// Valid returns all problems with the flags. It does not change the flags,
+// defaults are set by WithDefaults
+func (u UninstallFlags) Valid() error {
+	errs := u.CommonFlags.validate()
+	errs = append(errs, nonNegative("timeout", u.Timeout))
+	return Join(errs...)
+}
+
+// WithDefaults returns a copy of the flags with the default namespace set if it is empty
+func (u UninstallFlags) WithDefaults() UninstallFlags {
+	u.CommonFlags = u.CommonFlags.withDefaults()
+	return u
+}
+
+// AddRepoFlags defines flags supported by the add repository api
+type AddRepoFlags struct {
+	Username      string `json:"username,omitempty"`
+	Password      Secret `json:"-"`
+	ForceUpdate   bool   `json:"force_update"`
+	SkipTLSVerify bool   `json:"skip_tls_verify"`
+}
+
+// UpdateRepoFlags defines flags supported by the update repositories api
+type UpdateRepoFlags struct {
+	// Names of the repositories to update, all repositories are updated if empty
+	Names []string `json:"names,omitempty"`
+}
+
+func (a AddRepoFlags) Valid() error {
+	if a.Password != "" && a.Username == "" {
+		return errors.New("username is required when a password is set")
 	}
 	return nil
 }
 
-func (s *StatusFlags) Valid() error {
-	if s.KubeContext == "" {
-		return errors.New("kube context is a required parameter")
+// SearchFlags defines flags supported by the chart search api
+type SearchFlags struct {
+	// Versions returns all versions of the matching charts instead of only the latest
+	Versions bool `schema:"versions,omitempty"`
+
+	// Devel includes pre-release versions
+	Devel bool `schema:"devel,omitempty"`
+
+	// VersionConstraint filters the results by a semver constraint, such as "~1.2"
+	VersionConstraint string `schema:"-"`
+}
+
+func (s SearchFlags) Valid() error {
+	if s.VersionConstraint == "" {
+		return nil
 	}
-	if s.Namespace == "" {
-		s.Namespace = "default"
+	if _, err := semver.NewConstraint(s.VersionConstraint); err != nil {
+		return fmt.Errorf("invalid version constraint %q: %s", s.VersionConstraint, err)
 	}
 	return nil
 }
 
-func (u *UninstallFlags) Valid() error {
-	if u.KubeContext == "" {
-		return errors.New("kube context is a required parameter")
+// TemplateFlags defines flags supported by the template api
+type TemplateFlags struct {
+	// Version is either a concrete chart version or a semver constraint
+	Version string `json:"version"`
+
+	// Namespace the manifests are rendered for
+	Namespace string `json:"namespace,omitempty"`
+
+	// KubeVersion overrides the kubernetes version used for capabilities, such as "1.20.0"
+	KubeVersion string `json:"kube_version,omitempty"`
+
+	// APIVersions are added to the api versions used for capabilities, such as "monitoring.coreos.com/v1"
+	APIVersions []string `json:"api_versions,omitempty"`
+
+	IncludeCRDs bool `json:"include_crds"`
+}
+
+// Valid returns all problems with the flags. It does not change the flags,
+// defaults are set by WithDefaults
+func (t TemplateFlags) Valid() error {
+	errs := []error{validVersion(t.Version), validNamespace(t.Namespace)}
+	if t.KubeVersion != "" {
+		if _, err := semver.NewVersion(t.KubeVersion); err != nil {
+			errs = append(errs, fmt.Errorf("invalid kube version %q: %s", t.KubeVersion, err))
+		}
 	}
+	return Join(errs...)
+}
 
-	if u.Namespace == "" {
-		u.Namespace = "default"
+// WithDefaults returns a copy of the flags with the default namespace set if it is empty
+func (t TemplateFlags) WithDefaults() TemplateFlags {
+	if t.Namespace == "" {
+		t.Namespace = DefaultNamespace
 	}
+	return t
+}
 
-	return nil
+// TestFlags defines flags supported by the release test api
+type TestFlags struct {
+	// Timeout in seconds to wait for the tests to complete
+	Timeout int `json:"timeout,omitempty"`
+
+	// Logs returns the logs of the test pods
+	Logs bool `json:"logs"`
+	CommonFlags
+}
+
+// Valid returns all problems with the flags. It does not change the flags,
+// defaults are set by WithDefaults
+func (t TestFlags) Valid() error {
+	errs := t.CommonFlags.validate()
+	errs = append(errs, nonNegative("timeout", t.Timeout))
+	return Join(errs...)
+}
+
+// WithDefaults returns a copy of the flags with the default namespace set if it is empty
+func (t TestFlags) WithDefaults() TestFlags {
+	t.CommonFlags = t.CommonFlags.withDefaults()
+	return t
+}
+
+// HistoryFlags defines flags supported by the release history api
+type HistoryFlags struct {
+	// Max is the maximum number of revisions returned, the latest ones. Zero means all
+	Max int `schema:"max,omitempty"`
+	CommonFlags
+}
+
+// Valid returns all problems with the flags. It does not change the flags,
+// defaults are set by WithDefaults
+func (h HistoryFlags) Valid() error {
+	errs := h.CommonFlags.validate()
+	errs = append(errs, nonNegative("max", h.Max))
+	return Join(errs...)
+}
+
+// WithDefaults returns a copy of the flags with the default namespace set if it is empty
+func (h HistoryFlags) WithDefaults() HistoryFlags {
+	h.CommonFlags = h.CommonFlags.withDefaults()
+	return h
+}
+
+// RollbackFlags defines flags supported by the rollback api
+type RollbackFlags struct {
+	// Revision to roll back to. Zero rolls back to the previous revision
+	Revision int  `json:"revision"`
+	DryRun   bool `json:"dry_run"`
+
+	// Wait for all resources to be ready before marking the rollback as successful
+	Wait bool `json:"wait,omitempty"`
+
+	// Timeout in seconds for kubernetes operations and Wait
+	Timeout int `json:"timeout,omitempty"`
+
+	// Force resource updates through a replacement strategy
+	Force bool `json:"force,omitempty"`
+
+	DisableHooks  bool `json:"disable_hooks,omitempty"`
+	CleanupOnFail bool `json:"cleanup_on_fail,omitempty"`
+	CommonFlags
+}
+
+// Valid returns all problems with the flags. It does not change the flags,
+// defaults are set by WithDefaults
+func (r RollbackFlags) Valid() error {
+	errs := r.CommonFlags.validate()
+	errs = append(errs, nonNegative("revision", r.Revision), nonNegative("timeout", r.Timeout))
+	return Join(errs...)
+}
+
+// WithDefaults returns a copy of the flags with the default namespace set if it is empty
+func (r RollbackFlags) WithDefaults() RollbackFlags {
+	r.CommonFlags = r.CommonFlags.withDefaults()
+	return r
 }
@@ -0,0 +1,122 @@
+package flags
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidReturnsAllProblems(t *testing.T) {
+	fl := UpgradeFlags{
+		Version:         ">=1.x <",
+		Timeout:         -1,
+		ResetValues:     true,
+		ReuseValues:     true,
+		CreateNamespace: true,
+		CommonFlags:     CommonFlags{Namespace: "Payments"},
+	}
+
+	err := fl.Valid()
+
+	var errs Errors
+	require.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 6)
+	assert.Equal(t, "kube context is a required parameter", errs[0].Error())
+	assert.Contains(t, errs[1].Error(), `invalid namespace "Payments"`)
+	assert.Contains(t, errs[2].Error(), `invalid version constraint ">=1.x <"`)
+	assert.Equal(t, "timeout cannot be negative", errs[3].Error())
+	assert.Equal(t, "reset values and reuse values cannot be used together", errs[4].Error())
+	assert.Equal(t, "create namespace can only be used together with install", errs[5].Error())
+	assert.Equal(t, 5, strings.Count(err.Error(), "; "))
+}
+
+func TestValidDoesNotChangeTheFlags(t *testing.T) {
+	fl := InstallFlags{CommonFlags: CommonFlags{KubeContext: "staging"}}
+
+	assert.NoError(t, fl.Valid())
+	assert.Empty(t, fl.Namespace)
+}
+
+func TestWithDefaults(t *testing.T) {
+	t.Run("sets the default namespace", func(t *testing.T) {
+		fl := StatusFlags{CommonFlags: CommonFlags{KubeContext: "staging"}}
+
+		assert.Equal(t, DefaultNamespace, fl.WithDefaults().Namespace)
+		assert.Empty(t, fl.Namespace)
+	})
+
+	t.Run("keeps the namespace", func(t *testing.T) {
+		fl := UninstallFlags{CommonFlags: CommonFlags{KubeContext: "staging", Namespace: "apps"}}
+
+		assert.Equal(t, "apps", fl.WithDefaults().Namespace)
+	})
+
+	t.Run("does not set a namespace when listing all namespaces", func(t *testing.T) {
+		fl := ListFlags{AllNamespaces: true, CommonFlags: CommonFlags{KubeContext: "staging"}}
+
+		assert.Empty(t, fl.WithDefaults().Namespace)
+		assert.NoError(t, fl.WithDefaults().Valid())
+	})
+}
+
+func TestListFlagsValid(t *testing.T) {
+	fl := ListFlags{AllNamespaces: true, CommonFlags: CommonFlags{KubeContext: "staging", Namespace: "apps"}}
+
+	assert.EqualError(t, fl.Valid(), "namespace cannot be used together with all namespaces")
+}
+
+func TestValidReleaseName(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  string
+	}{
+		{name: "api"},
+		{name: "payments-api.v2"},
+		{name: strings.Repeat("a", 53)},
+		{name: "", err: "name cannot be empty"},
+		{name: strings.Repeat("a", 54), err: "must be no more than 53 characters"},
+		{name: "Api", err: "must consist of lower case alphanumeric characters"},
+		{name: "api_v2", err: "must consist of lower case alphanumeric characters"},
+		{name: "-api", err: "must consist of lower case alphanumeric characters"},
+	}
+
+	for _, tc := range testCases {
+		err := ValidReleaseName(tc.name)
+		if tc.err == "" {
+			assert.NoError(t, err, tc.name)
+		} else {
+			require.Error(t, err, tc.name)
+			assert.Contains(t, err.Error(), tc.err)
+		}
+	}
+}
+
+func TestValidRepoName(t *testing.T) {
+	assert.NoError(t, ValidRepoName("stable"))
+	assert.NoError(t, ValidRepoName("my charts"))
+	assert.EqualError(t, ValidRepoName(""), "name cannot be empty")
+	assert.EqualError(t, ValidRepoName("bitnami/charts"), `invalid repository name "bitnami/charts": must not contain '/'`)
+	assert.EqualError(t, ValidRepoName(".."), `invalid repository name ".."`)
+	assert.EqualError(t, ValidRepoName("update"), `invalid repository name "update": the name is reserved`)
+}
+
+func TestJoin(t *testing.T) {
+	first, second, third := errors.New("first"), errors.New("second"), errors.New("third")
+
+	assert.NoError(t, Join(nil, nil))
+	assert.Equal(t, Errors{first}, Join(first, nil))
+	assert.Equal(t, Errors{first, second, third}, Join(first, Errors{second, third}))
+	assert.EqualError(t, Join(first, second), "first; second")
+}
+
+func TestIsVersionConstraint(t *testing.T) {
+	for _, version := range []string{"~1.2", "^1.2.0", ">=1.4 <2", ">1.0, <2.0", "1.2.x", "1.X", "*", "1.2 || 2.0"} {
+		assert.True(t, IsVersionConstraint(version), version)
+	}
+	for _, version := range []string{"", "1.2.3", "v1.2.3", "1.2", "1.0.0-fix", "2021.04.1-build.x7"} {
+		assert.False(t, IsVersionConstraint(version), version)
+	}
+}
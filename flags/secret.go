@@ -0,0 +1,37 @@
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const redacted = "[REDACTED]"
+
+// Secret is a string, such as a token, that is redacted when it is formatted or
+// marshaled to json, so that it does not end up in logs or error messages.
+// Use Reveal to get the value
+type Secret string
+
+// Reveal returns the value of the secret
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// String returns a placeholder for a set secret, and an empty string otherwise
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// Format redacts the secret for every verb, including %#v and %x
+func (s Secret) Format(f fmt.State, verb rune) {
+	_, _ = io.WriteString(f, s.String())
+}
+
+// MarshalJSON redacts the secret
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
@@ -0,0 +1,32 @@
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretIsRedacted(t *testing.T) {
+	fl := InstallFlags{CommonFlags: CommonFlags{KubeContext: "staging", KubeToken: "kube-token"}}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x"} {
+		assert.NotContains(t, fmt.Sprintf(format, fl), "kube-token", format)
+		assert.NotContains(t, fmt.Sprintf(format, fl.KubeToken), "kube-token", format)
+	}
+	assert.Equal(t, "[REDACTED]", fmt.Sprint(fl.KubeToken))
+	assert.Equal(t, "kube-token", fl.KubeToken.Reveal())
+
+	data, err := json.Marshal(struct{ Token Secret }{fl.KubeToken})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Token": "[REDACTED]"}`, string(data))
+}
+
+func TestEmptySecretIsEmpty(t *testing.T) {
+	var s Secret
+
+	assert.Equal(t, "", fmt.Sprint(s))
+	assert.Equal(t, "", s.String())
+}
@@ -0,0 +1,119 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultNamespace is used by WithDefaults when no namespace is set
+const DefaultNamespace = "default"
+
+var (
+	dns1123Label     = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	dns1123Subdomain = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// maxReleaseNameLength is the limit helm puts on release names, which leaves room
+// for the suffixes charts add to the names of their resources
+const maxReleaseNameLength = 53
+
+// Errors holds all problems found while validating flags
+type Errors []error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Join combines errors into Errors, flattening nested Errors and dropping nils.
+// It returns nil if there is no error
+func Join(errs ...error) error {
+	var joined Errors
+	for _, err := range errs {
+		var nested Errors
+		if errors.As(err, &nested) {
+			joined = append(joined, nested...)
+		} else if err != nil {
+			joined = append(joined, err)
+		}
+	}
+	if len(joined) == 0 {
+		return nil
+	}
+	return joined
+}
+
+// ValidReleaseName checks that a release name is a DNS-1123 subdomain of at most 53 characters
+func ValidReleaseName(name string) error {
+	if name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if len(name) > maxReleaseNameLength {
+		return fmt.Errorf("invalid release name %q: must be no more than %d characters", name, maxReleaseNameLength)
+	}
+	if !dns1123Subdomain.MatchString(name) {
+		return fmt.Errorf("invalid release name %q: must consist of lower case alphanumeric characters, '-' or '.', and start and end with an alphanumeric character", name)
+	}
+	return nil
+}
+
+// reservedRepoNames clash with the routes of the repository apis
+var reservedRepoNames = map[string]bool{"update": true}
+
+// ValidRepoName checks a chart repository name with the rules of helm, which does not
+// allow slashes, and rejects names that clash with the routes of the repository apis
+func ValidRepoName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("name cannot be empty")
+	case strings.Contains(name, "/"):
+		return fmt.Errorf("invalid repository name %q: must not contain '/'", name)
+	case name == "." || name == "..":
+		return fmt.Errorf("invalid repository name %q", name)
+	case reservedRepoNames[name]:
+		return fmt.Errorf("invalid repository name %q: the name is reserved", name)
+	}
+	return nil
+}
+
+// validNamespace checks that a namespace is a DNS-1123 label. An empty namespace is
+// valid, as it is set to the default namespace by WithDefaults
+func validNamespace(namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+	if len(namespace) > 63 || !dns1123Label.MatchString(namespace) {
+		return fmt.Errorf("invalid namespace %q: must consist of at most 63 lower case alphanumeric characters or '-', and start and end with an alphanumeric character", namespace)
+	}
+	return nil
+}
+
+func (c CommonFlags) validate() []error {
+	var errs []error
+	if c.KubeContext == "" {
+		errs = append(errs, errors.New("kube context is a required parameter"))
+	}
+	if err := validNamespace(c.Namespace); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func (c CommonFlags) withDefaults() CommonFlags {
+	if c.Namespace == "" {
+		c.Namespace = DefaultNamespace
+	}
+	return c
+}
+
+func nonNegative(name string, value int) error {
+	if value < 0 {
+		return fmt.Errorf("%s cannot be negative", name)
+	}
+	return nil
+}
@@ -3,6 +3,10 @@ module github.com/gojekfarm/albatross-client-go
 go 1.14
 
 require (
+	filippo.io/age v1.0.0
+	github.com/Masterminds/semver/v3 v3.1.1
 	github.com/gorilla/schema v1.2.0
 	github.com/stretchr/testify v1.6.1
+	github.com/xeipuuv/gojsonschema v1.2.0
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
@@ -0,0 +1,153 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/config"
+	"github.com/gojekfarm/albatross-client-go/logger"
+)
+
+// ErrCircuitOpen is returned without sending the request when the circuit
+// breaker of the host is open
+type ErrCircuitOpen struct {
+	Host string
+
+	// RetryAfter is the time at which trial requests are let through again
+	RetryAfter time.Time
+}
+
+func (e *ErrCircuitOpen) Error() string {
+	return fmt.Sprintf("circuit breaker is open for albatross host %s until %s", e.Host, e.RetryAfter.Format(time.RFC3339))
+}
+
+// circuitBreakers keeps a circuit breaker for every host the client sends requests to
+type circuitBreakers struct {
+	mu       sync.Mutex
+	policy   *config.CircuitBreaker
+	breakers map[string]*circuitBreaker
+	now      func() time.Time
+	logger   logger.Logger
+}
+
+// circuitBreaker tracks the failures of a single host
+type circuitBreaker struct {
+	mu        sync.Mutex
+	host      string
+	parent    *circuitBreakers
+	state     config.CircuitState
+	failures  int
+	openUntil time.Time
+	trials    int
+}
+
+func newCircuitBreakers(policy *config.CircuitBreaker, logger logger.Logger) *circuitBreakers {
+	return &circuitBreakers{
+		policy:   policy,
+		breakers: map[string]*circuitBreaker{},
+		now:      time.Now,
+		logger:   logger,
+	}
+}
+
+// get returns the circuit breaker of the host, creating a closed one if it does not exist
+func (b *circuitBreakers) get(host string) *circuitBreaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	breaker, ok := b.breakers[host]
+	if !ok {
+		breaker = &circuitBreaker{host: host, parent: b, state: config.CircuitClosed}
+		b.breakers[host] = breaker
+	}
+	return breaker
+}
+
+// allow returns an ErrCircuitOpen if the request must not be sent.
+// Every allowed request must be followed by a call to record
+func (cb *circuitBreaker) allow() error {
+	cb.mu.Lock()
+	from := cb.state
+
+	switch cb.state {
+	case config.CircuitOpen:
+		if cb.parent.now().Before(cb.openUntil) {
+			err := &ErrCircuitOpen{Host: cb.host, RetryAfter: cb.openUntil}
+			cb.mu.Unlock()
+			return err
+		}
+		cb.state = config.CircuitHalfOpen
+		cb.trials = 0
+		fallthrough
+	case config.CircuitHalfOpen:
+		if cb.trials >= cb.parent.halfOpenRequests() {
+			err := &ErrCircuitOpen{Host: cb.host, RetryAfter: cb.openUntil}
+			cb.mu.Unlock()
+			cb.parent.changed(cb.host, from, cb.state)
+			return err
+		}
+		cb.trials++
+	}
+
+	to := cb.state
+	cb.mu.Unlock()
+	cb.parent.changed(cb.host, from, to)
+	return nil
+}
+
+// record updates the circuit with the outcome of a request. A request that failed because
+// its context is done is not counted, it only gives back its trial in the half-open state
+func (cb *circuitBreaker) record(ctx context.Context, resp *http.Response, err error) {
+	cb.mu.Lock()
+	if cancelled(ctx, err) {
+		if cb.state == config.CircuitHalfOpen && cb.trials > 0 {
+			cb.trials--
+		}
+		cb.mu.Unlock()
+		return
+	}
+	from := cb.state
+
+	if err != nil || resp.StatusCode >= 500 {
+		cb.failures++
+		if cb.state == config.CircuitHalfOpen || cb.failures >= cb.parent.policy.FailureThreshold {
+			cb.state = config.CircuitOpen
+			cb.openUntil = cb.parent.now().Add(cb.parent.policy.CoolDown)
+		}
+	} else {
+		cb.failures = 0
+		cb.state = config.CircuitClosed
+	}
+	if cb.state == config.CircuitHalfOpen && cb.trials > 0 {
+		cb.trials--
+	}
+
+	to := cb.state
+	cb.mu.Unlock()
+	cb.parent.changed(cb.host, from, to)
+}
+
+func (b *circuitBreakers) halfOpenRequests() int {
+	if b.policy.HalfOpenRequests <= 0 {
+		return 1
+	}
+	return b.policy.HalfOpenRequests
+}
+
+// changed logs a state change and notifies the OnStateChange callback
+func (b *circuitBreakers) changed(host string, from config.CircuitState, to config.CircuitState) {
+	if from == to {
+		return
+	}
+	if to == config.CircuitOpen {
+		b.logger.Errorf("circuit breaker for albatross host %s changed from %s to %s", host, from, to)
+	} else {
+		b.logger.Infof("circuit breaker for albatross host %s changed from %s to %s", host, from, to)
+	}
+	if b.policy.OnStateChange != nil {
+		b.policy.OnStateChange(host, from, to)
+	}
+}
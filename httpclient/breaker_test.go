@@ -0,0 +1,187 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/config"
+	"github.com/gojekfarm/albatross-client-go/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type stateChange struct {
+	from config.CircuitState
+	to   config.CircuitState
+}
+
+func newTestBreakers(now *time.Time, changes *[]stateChange) *circuitBreakers {
+	breakers := newCircuitBreakers(&config.CircuitBreaker{
+		FailureThreshold: 2,
+		CoolDown:         time.Minute,
+		OnStateChange: func(host string, from config.CircuitState, to config.CircuitState) {
+			*changes = append(*changes, stateChange{from, to})
+		},
+	}, &logger.DefaultLogger{})
+	breakers.now = func() time.Time { return *now }
+	return breakers
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	now := time.Now()
+	var changes []stateChange
+	breaker := newTestBreakers(&now, &changes).get("albatross:8080")
+	networkError := errors.New("Network Error")
+
+	require.NoError(t, breaker.allow())
+	breaker.record(context.Background(), nil, networkError)
+	assert.Equal(t, config.CircuitClosed, breaker.state)
+
+	require.NoError(t, breaker.allow())
+	breaker.record(context.Background(), &http.Response{StatusCode: 502}, nil)
+	assert.Equal(t, config.CircuitOpen, breaker.state)
+
+	err := breaker.allow()
+	var circuitErr *ErrCircuitOpen
+	require.True(t, errors.As(err, &circuitErr))
+	assert.Equal(t, "albatross:8080", circuitErr.Host)
+	assert.Equal(t, now.Add(time.Minute), circuitErr.RetryAfter)
+
+	t.Run("When a trial request fails after the cool down", func(t *testing.T) {
+		now = now.Add(2 * time.Minute)
+		require.NoError(t, breaker.allow())
+		assert.Equal(t, config.CircuitHalfOpen, breaker.state)
+		assert.Error(t, breaker.allow(), "only one trial request is allowed")
+
+		breaker.record(context.Background(), nil, networkError)
+		assert.Equal(t, config.CircuitOpen, breaker.state)
+		assert.Error(t, breaker.allow())
+	})
+
+	t.Run("When a trial request succeeds after the cool down", func(t *testing.T) {
+		now = now.Add(2 * time.Minute)
+		require.NoError(t, breaker.allow())
+		breaker.record(context.Background(), &http.Response{StatusCode: 200}, nil)
+		assert.Equal(t, config.CircuitClosed, breaker.state)
+		assert.NoError(t, breaker.allow())
+	})
+
+	assert.Equal(t, []stateChange{
+		{config.CircuitClosed, config.CircuitOpen},
+		{config.CircuitOpen, config.CircuitHalfOpen},
+		{config.CircuitHalfOpen, config.CircuitOpen},
+		{config.CircuitOpen, config.CircuitHalfOpen},
+		{config.CircuitHalfOpen, config.CircuitClosed},
+	}, changes)
+}
+
+func TestCircuitBreakerIgnoresCancelledRequests(t *testing.T) {
+	now := time.Now()
+	var changes []stateChange
+	breaker := newTestBreakers(&now, &changes).get("albatross:8080")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, breaker.allow())
+		breaker.record(ctx, nil, context.Canceled)
+	}
+	assert.Equal(t, config.CircuitClosed, breaker.state)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, breaker.allow())
+		breaker.record(context.Background(), nil, errors.New("Network Error"))
+	}
+	require.Equal(t, config.CircuitOpen, breaker.state)
+
+	t.Run("When a trial request is cancelled after the cool down", func(t *testing.T) {
+		now = now.Add(2 * time.Minute)
+		require.NoError(t, breaker.allow())
+		breaker.record(ctx, nil, context.Canceled)
+
+		assert.Equal(t, config.CircuitHalfOpen, breaker.state)
+		require.NoError(t, breaker.allow(), "the cancelled trial is given back")
+		breaker.record(context.Background(), &http.Response{StatusCode: 200}, nil)
+		assert.Equal(t, config.CircuitClosed, breaker.state)
+	})
+}
+
+func TestCircuitBreakersAreKeptPerHost(t *testing.T) {
+	now := time.Now()
+	var changes []stateChange
+	breakers := newTestBreakers(&now, &changes)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, breakers.get("albatross-a").allow())
+		breakers.get("albatross-a").record(context.Background(), nil, errors.New("Network Error"))
+	}
+
+	assert.Error(t, breakers.get("albatross-a").allow())
+	assert.NoError(t, breakers.get("albatross-b").allow())
+}
+
+func TestHttpClientFailsFastWhenCircuitIsOpen(t *testing.T) {
+	now := time.Now()
+	var changes []stateChange
+	mc := new(mockClient)
+	mc.On("Do", mock.Anything).Return(&http.Response{
+		StatusCode: 500,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("error"))),
+	}, nil).Twice()
+	client := &Client{
+		client:   mc,
+		retry:    &config.Retry{RetryCount: 3, Backoff: time.Millisecond},
+		logger:   &logger.DefaultLogger{},
+		breakers: newTestBreakers(&now, &changes),
+	}
+
+	for i := 0; i < 2; i++ {
+		_, _, err := client.Send(context.Background(), "http://localhost:444", "GET", nil)
+		require.NoError(t, err)
+	}
+	_, data, err := client.Send(context.Background(), "http://localhost:444", "GET", nil)
+
+	var circuitErr *ErrCircuitOpen
+	assert.True(t, errors.As(err, &circuitErr))
+	assert.Nil(t, data)
+	mc.AssertExpectations(t)
+}
+
+func TestHttpClientSkipsHostsWithOpenCircuit(t *testing.T) {
+	now := time.Now()
+	var changes []stateChange
+	mc := new(mockClient)
+	mc.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.URL.Host == "albatross-b:8080"
+	})).Return(&http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("ok"))),
+	}, nil).Once()
+	breakers := newTestBreakers(&now, &changes)
+	client := &Client{
+		client:   mc,
+		retry:    &config.Retry{RetryCount: 1, Backoff: time.Millisecond},
+		logger:   &logger.DefaultLogger{},
+		breakers: breakers,
+		hosts:    newTestHostPool(t, config.Failover, &now),
+	}
+	for i := 0; i < 2; i++ {
+		require.NoError(t, breakers.get("albatross-a:8080").allow())
+		breakers.get("albatross-a:8080").record(context.Background(), nil, errors.New("Network Error"))
+	}
+
+	// The pool still considers the host healthy, its breaker is open
+	_, data, err := client.Send(context.Background(), "/clusters/staging/releases", "GET", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ok"), data)
+	assert.Equal(t, now.Add(time.Minute), client.hosts.hosts[0].unhealthyUntil)
+	assert.Equal(t, "albatross-b:8080", pickHost(client.hosts))
+	mc.AssertExpectations(t)
+}
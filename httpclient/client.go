@@ -2,6 +2,8 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -20,9 +22,13 @@ type client interface {
 // Client acts as a wrapper around the net/http.Client to take care of exponential retries.
 // TODO: Discuss if we should use go-retryablehttp
 type Client struct {
-	client client
-	retry  *config.Retry
-	logger logger.Logger
+	client   client
+	retry    *config.Retry
+	logger   logger.Logger
+	auth     *config.Auth
+	hosts    *hostPool
+	breakers *circuitBreakers
+	limiters *limiters
 }
 
 // Request executes a given request with the provided retry policy
@@ -32,8 +38,19 @@ type Client struct {
 // json body unless under exceptional circumstances. The users can check the response status code
 // and parse the bytestream accordingly.
 // The client can be extended to handle authentication failures
-func (c *Client) Send(url string, method string, body io.Reader) (*http.Response, []byte, error) {
-	resp, err := c.send(url, method, body)
+// If rate or concurrency limits are configured, Send waits for them before sending the request,
+// and returns early with the context error if ctx is done in the meantime
+func (c *Client) Send(ctx context.Context, url string, method string, body io.Reader) (*http.Response, []byte, error) {
+	if c.limiters != nil {
+		release, err := c.limiters.get(kubeContextFrom(ctx)).acquire(ctx)
+		if err != nil {
+			c.logger.Errorf("Error waiting for the request rate limit: %s", err)
+			return nil, nil, err
+		}
+		defer release()
+	}
+
+	resp, err := c.send(ctx, url, method, body)
 	if err != nil {
 		c.logger.Errorf("Error sending request: %s", err)
 		return nil, nil, err
@@ -56,22 +73,119 @@ func (c *Client) Send(url string, method string, body io.Reader) (*http.Response
 	return resp, data, nil
 }
 
-func (c *Client) send(url string, method string, body io.Reader) (*http.Response, error) {
+func (c *Client) send(ctx context.Context, url string, method string, body io.Reader) (*http.Response, error) {
 	if c.retry == nil {
-		return c.sendOnce(url, method, body)
+		return c.sendOnce(ctx, url, method, body)
+	}
+
+	return c.sendWithRetry(ctx, url, method, body)
+}
+
+// newRequest creates a request and sets the auth headers on it.
+// When the client has multiple hosts, url only holds the path and query of the request
+// and is resolved against the host picked for this attempt, which is returned with the request
+func (c *Client) newRequest(ctx context.Context, url string, method string, body io.Reader) (*http.Request, *host, error) {
+	var picked *host
+	if c.hosts != nil {
+		picked = c.hosts.pick()
+		var err error
+		if url, err = picked.resolve(url); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	setKubeCredentials(ctx, request.Header)
+	setIfNoneMatch(ctx, request.Header)
+	if c.auth != nil {
+		if c.auth.Token != "" {
+			request.Header.Set("Authorization", "Bearer "+c.auth.Token.Reveal())
+		} else if c.auth.Username != "" {
+			request.SetBasicAuth(c.auth.Username, c.auth.Password.Reveal())
+		}
+	}
+	return request, picked, nil
+}
+
+// do sends the request and records the outcome against the host that served it, if the
+// client has multiple hosts. If the circuit breaker of the host is open, it fails fast with ErrCircuitOpen.
+// Requests that fail because their context is done say nothing about the host, so they are not recorded
+func (c *Client) do(request *http.Request, picked *host) (*http.Response, error) {
+	var breaker *circuitBreaker
+	if c.breakers != nil {
+		breaker = c.breakers.get(request.URL.Host)
+		if err := breaker.allow(); err != nil {
+			var circuitErr *ErrCircuitOpen
+			if picked != nil && errors.As(err, &circuitErr) {
+				// The host pool skips the host until the breaker lets requests through again
+				c.hosts.skipUntil(picked, circuitErr.RetryAfter)
+			}
+			return nil, err
+		}
+	}
+
+	resp, err := c.client.Do(request)
+	if breaker != nil {
+		breaker.record(request.Context(), resp, err)
+	}
+	if picked != nil && !cancelled(request.Context(), err) {
+		c.hosts.observe(picked, resp, err)
+	}
+	return resp, err
+}
+
+// cancelled reports whether the request failed because its context is done. Timeouts of
+// the http client wrap context.DeadlineExceeded as well, but they are failures of the host,
+// so the context of the request is checked rather than the error
+func cancelled(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() != nil
+}
+
+// sendOnce sends the request without retries. When the client has multiple hosts and
+// the request fails with a network error or a 5xx response, it is sent once more to the
+// next healthy host, if there is one. Further attempts need a retry policy
+func (c *Client) sendOnce(ctx context.Context, url string, method string, body io.Reader) (*http.Response, error) {
+	if c.hosts == nil {
+		request, picked, err := c.newRequest(ctx, url, method, body)
+		if err != nil {
+			c.logger.Errorf("Unable to create a new request: %s", err)
+			return nil, err
+		}
+		return c.do(request, picked)
+	}
+
+	var reqBytes []byte
+	if body != nil {
+		var err error
+		if reqBytes, err = ioutil.ReadAll(body); err != nil {
+			return nil, fmt.Errorf("Error reading the request body: %s", err)
+		}
 	}
 
-	return c.sendWithRetry(url, method, body)
+	resp, err := c.sendBytes(ctx, url, method, reqBytes)
+	if cancelled(ctx, err) || (err == nil && resp.StatusCode < 500) || !c.hosts.healthy() {
+		return resp, err
+	}
+	if err != nil {
+		c.logger.Errorf("Error connecting to albatross API: %s - failing over to the next host", err)
+	} else {
+		c.logger.Errorf("server error from albatross API: %d - failing over to the next host", resp.StatusCode)
+		resp.Body.Close()
+	}
+	return c.sendBytes(ctx, url, method, reqBytes)
 }
 
-func (c *Client) sendOnce(url string, method string, body io.Reader) (*http.Response, error) {
-	request, err := http.NewRequest(method, url, body)
+func (c *Client) sendBytes(ctx context.Context, url string, method string, reqBytes []byte) (*http.Response, error) {
+	request, picked, err := c.newRequest(ctx, url, method, bytes.NewBuffer(reqBytes))
 	if err != nil {
 		c.logger.Errorf("Unable to create a new request: %s", err)
 		return nil, err
 	}
-
-	return c.client.Do(request)
+	return c.do(request, picked)
 }
 
 func (c *Client) getBackoffForRetry(count int) time.Duration {
@@ -81,7 +195,7 @@ func (c *Client) getBackoffForRetry(count int) time.Duration {
 	return time.Duration(math.Exp2(float64(count))) * c.retry.Backoff
 }
 
-func (c *Client) sendWithRetry(url string, method string, body io.Reader) (*http.Response, error) {
+func (c *Client) sendWithRetry(ctx context.Context, url string, method string, body io.Reader) (*http.Response, error) {
 	// reqBytes is used to populate the body for the request for each retry,
 	var reqBytes []byte = nil
 
@@ -97,6 +211,8 @@ func (c *Client) sendWithRetry(url string, method string, body io.Reader) (*http
 		timeout := c.getBackoffForRetry(count)
 
 		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		case <-time.After(timeout):
 			// We are creating a new request for every retry, which is not ideal,
 			// but the Request struct does not provide convenient methods to reset seek offset of
@@ -105,13 +221,18 @@ func (c *Client) sendWithRetry(url string, method string, body io.Reader) (*http
 			// needs to be drained as well to prevent corruption of response object.
 			// For now, adopting NewRequest on each retry. We can easily adopt
 			// hashicorp/retryablehttp here, it satifies the default http client(and our) interface.
-			request, err := http.NewRequest(method, url, bytes.NewBuffer(reqBytes))
+			request, picked, err := c.newRequest(ctx, url, method, bytes.NewBuffer(reqBytes))
 			if err != nil {
 				c.logger.Errorf("Unable to create a new request: %s", err)
 				return nil, err
 			}
 
-			resp, err := c.client.Do(request)
+			resp, err := c.do(request, picked)
+			var circuitErr *ErrCircuitOpen
+			if errors.As(err, &circuitErr) && c.hosts == nil {
+				// Retrying is pointless until the circuit lets requests through again
+				return nil, err
+			}
 			if err != nil {
 				c.logger.Errorf("Error connecting to albatross API: %s - retrying", err)
 				retryError = err
@@ -127,13 +248,45 @@ func (c *Client) sendWithRetry(url string, method string, body io.Reader) (*http
 
 // NewClient returns a new http client
 // It sets the client timeout using the timeout specified in config
-// and sets retry policy
-func NewClient(config *config.Config) *Client {
-	return &Client{
-		client: &http.Client{
-			Timeout: config.Timeout,
-		},
+// and sets retry policy, rate limits, circuit breaker, auth credentials, tls settings and the hosts to balance over.
+// It returns an error if the tls certificates cannot be loaded
+func NewClient(config *config.Config) (*Client, error) {
+	httpClient := &http.Client{
+		Timeout: config.Timeout,
+	}
+
+	if config.TLS != nil {
+		tlsConfig, err := config.TLS.ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		httpClient.Transport = transport
+	}
+
+	client := &Client{
+		client: httpClient,
 		retry:  config.Retry,
 		logger: config.Logger,
+		auth:   config.Auth,
+	}
+
+	if config.RateLimit != nil {
+		client.limiters = newLimiters(config.RateLimit)
 	}
+
+	if config.CircuitBreaker != nil {
+		client.breakers = newCircuitBreakers(config.CircuitBreaker, config.Logger)
+	}
+
+	if hosts := config.HostList(); len(hosts) > 1 {
+		pool, err := newHostPool(hosts, config.HostPolicy, config.Logger)
+		if err != nil {
+			return nil, err
+		}
+		client.hosts = pool
+	}
+
+	return client, nil
 }
@@ -2,6 +2,7 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io/ioutil"
 	"net/http"
@@ -42,7 +43,7 @@ func TestHttpClientSendOnSuccess(t *testing.T) {
 		logger: &logger.DefaultLogger{},
 	}
 
-	resp, data, err := client.Send("http://localhost:444", "GET", bytes.NewReader([]byte("abcde")))
+	resp, data, err := client.Send(context.Background(), "http://localhost:444", "GET", bytes.NewReader([]byte("abcde")))
 
 	assert.NoError(t, err)
 	assert.Equal(t, data, []byte("abcde"))
@@ -68,7 +69,7 @@ func TestHttpClientSendWithRetry(t *testing.T) {
 			logger: &logger.DefaultLogger{},
 		}
 
-		resp, err := httpClient.sendWithRetry("http://localhost:444", "GET", nil)
+		resp, err := httpClient.sendWithRetry(context.Background(), "http://localhost:444", "GET", nil)
 
 		assert.NoError(t, err)
 		assert.Equal(t, resp, response)
@@ -92,7 +93,7 @@ func TestHttpClientSendWithRetry(t *testing.T) {
 			logger: &logger.DefaultLogger{},
 		}
 
-		resp, err := client.sendWithRetry("http://localhost:444", "GET", nil)
+		resp, err := client.sendWithRetry(context.Background(), "http://localhost:444", "GET", nil)
 
 		assert.Nil(t, err)
 		assert.Equal(t, resp, response)
@@ -116,7 +117,7 @@ func TestHttpClientSendWithRetry(t *testing.T) {
 			logger: &logger.DefaultLogger{},
 		}
 
-		resp, err := client.sendWithRetry("http://localhost:444", "GET", nil)
+		resp, err := client.sendWithRetry(context.Background(), "http://localhost:444", "GET", nil)
 
 		assert.Nil(t, err)
 		assert.Equal(t, resp, response)
@@ -135,7 +136,7 @@ func TestHttpClientSendWithRetry(t *testing.T) {
 			logger: &logger.DefaultLogger{},
 		}
 
-		resp, err := client.sendWithRetry("http://localhost:444", "GET", nil)
+		resp, err := client.sendWithRetry(context.Background(), "http://localhost:444", "GET", nil)
 
 		assert.Error(t, err)
 		assert.EqualError(t, err, "Max retries exceeded: Network Error")
@@ -154,7 +155,7 @@ func TestHttpClientSendWithRetry(t *testing.T) {
 			logger: &logger.DefaultLogger{},
 		}
 
-		resp, err := client.sendWithRetry("http://localhost:444", "GET", nil)
+		resp, err := client.sendWithRetry(context.Background(), "http://localhost:444", "GET", nil)
 
 		assert.Error(t, err)
 		assert.EqualError(t, err, "Max retries exceeded: Network Error")
@@ -181,7 +182,7 @@ func TestHttpClientSendWithRetry(t *testing.T) {
 			logger: &logger.DefaultLogger{},
 		}
 
-		resp, err := client.sendWithRetry("http://localhost:444", "GET", nil)
+		resp, err := client.sendWithRetry(context.Background(), "http://localhost:444", "GET", nil)
 
 		assert.NoError(t, err)
 		assert.Equal(t, resp, response)
@@ -206,7 +207,7 @@ func TestHttpClientSendOnServerError(t *testing.T) {
 		logger: &logger.DefaultLogger{},
 	}
 
-	resp, data, err := client.Send("http://localhost:444", "GET", bytes.NewReader([]byte("abcde")))
+	resp, data, err := client.Send(context.Background(), "http://localhost:444", "GET", bytes.NewReader([]byte("abcde")))
 
 	assert.Nil(t, err)
 	assert.Equal(t, data, []byte("abcde"))
@@ -231,7 +232,7 @@ func TestHttpClientSendOnBadRequest(t *testing.T) {
 		logger: &logger.DefaultLogger{},
 	}
 
-	resp, data, err := client.Send("http://localhost:444", "GET", bytes.NewReader([]byte("abcde")))
+	resp, data, err := client.Send(context.Background(), "http://localhost:444", "GET", bytes.NewReader([]byte("abcde")))
 
 	assert.Nil(t, err)
 	assert.NotNil(t, data)
@@ -252,7 +253,7 @@ func TestHttpClientSendOnNetworkErrorWithRetries(t *testing.T) {
 		logger: &logger.DefaultLogger{},
 	}
 
-	_, data, err := client.Send("http://localhost:444", "GET", bytes.NewReader([]byte("abcde")))
+	_, data, err := client.Send(context.Background(), "http://localhost:444", "GET", bytes.NewReader([]byte("abcde")))
 
 	assert.Error(t, err)
 	assert.EqualError(t, err, "Max retries exceeded: Network Error")
@@ -269,7 +270,7 @@ func TestHttpClientSendOnNetworkErrorWithoutRetries(t *testing.T) {
 		logger: &logger.DefaultLogger{},
 	}
 
-	_, data, err := client.Send("http://localhost:444", "GET", bytes.NewReader([]byte("abcde")))
+	_, data, err := client.Send(context.Background(), "http://localhost:444", "GET", bytes.NewReader([]byte("abcde")))
 
 	assert.Error(t, err)
 	assert.EqualError(t, err, "Network Error")
@@ -296,9 +297,110 @@ func TestHttpClientSendOnNetworkErrorWithRecovery(t *testing.T) {
 		logger: &logger.DefaultLogger{},
 	}
 
-	resp, data, err := client.Send("http://localhost:444", "GET", bytes.NewReader([]byte("abcde")))
+	resp, data, err := client.Send(context.Background(), "http://localhost:444", "GET", bytes.NewReader([]byte("abcde")))
 
 	assert.NoError(t, err)
 	assert.Equal(t, data, []byte("abcde"))
 	assert.Equal(t, resp.StatusCode, 200)
 }
+
+func TestHttpClientSendsAuthHeaders(t *testing.T) {
+	t.Run("When a bearer token is configured", func(t *testing.T) {
+		mc := new(mockClient)
+		response := &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("ab"))),
+		}
+		mc.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+			return req.Header.Get("Authorization") == "Bearer token"
+		})).Return(response, nil).Once()
+		client := &Client{
+			client: mc,
+			logger: &logger.DefaultLogger{},
+			auth:   &config.Auth{Token: "token"},
+		}
+
+		_, _, err := client.Send(context.Background(), "http://localhost:444", "GET", nil)
+
+		assert.NoError(t, err)
+		mc.AssertExpectations(t)
+	})
+
+	t.Run("When basic auth is configured with retries", func(t *testing.T) {
+		mc := new(mockClient)
+		response := &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("ab"))),
+		}
+		mc.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+			username, password, ok := req.BasicAuth()
+			return ok && username == "user" && password == "secret"
+		})).Return(response, nil).Once()
+		client := &Client{
+			client: mc,
+			retry:  &config.Retry{RetryCount: 1},
+			logger: &logger.DefaultLogger{},
+			auth:   &config.Auth{Username: "user", Password: "secret"},
+		}
+
+		_, _, err := client.Send(context.Background(), "http://localhost:444", "GET", nil)
+
+		assert.NoError(t, err)
+		mc.AssertExpectations(t)
+	})
+}
+
+func TestHttpClientSendsKubeCredentialHeaders(t *testing.T) {
+	mc := new(mockClient)
+	response := &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("ab"))),
+	}
+	mc.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.Header.Get(HeaderKubeToken) == "kube-token" &&
+			req.Header.Get(HeaderKubeAPIServer) == "https://kube.staging:6443" &&
+			req.Header.Get("Authorization") == "Bearer token"
+	})).Return(response, nil).Once()
+	client := &Client{
+		client: mc,
+		logger: &logger.DefaultLogger{},
+		auth:   &config.Auth{Token: "token"},
+	}
+	ctx := WithKubeCredentials(context.Background(), "kube-token", "https://kube.staging:6443")
+
+	_, _, err := client.Send(ctx, "http://localhost:444", "GET", nil)
+
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+}
+
+func TestHttpClientSendsIfNoneMatchHeader(t *testing.T) {
+	mc := new(mockClient)
+	response := &http.Response{
+		StatusCode: http.StatusNotModified,
+		Body:       http.NoBody,
+	}
+	mc.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.Header.Get("If-None-Match") == `"v1"`
+	})).Return(response, nil).Once()
+	client := &Client{
+		client: mc,
+		logger: &logger.DefaultLogger{},
+	}
+
+	resp, data, err := client.Send(WithIfNoneMatch(context.Background(), `"v1"`), "http://localhost:444", "GET", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Empty(t, data)
+	mc.AssertExpectations(t)
+}
+
+func TestNewClientFailsForMissingTLSFiles(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.TLS = &config.TLS{CAFile: "/does/not/exist.pem"}
+
+	_, err := NewClient(cfg)
+
+	assert.Error(t, err)
+}
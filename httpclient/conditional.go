@@ -0,0 +1,25 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type ifNoneMatchKey struct{}
+
+// WithIfNoneMatch returns a context carrying the entity tag of a cached response. It is sent
+// in the If-None-Match header, so that the server can answer 304 Not Modified if the
+// response did not change
+func WithIfNoneMatch(ctx context.Context, etag string) context.Context {
+	if etag == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ifNoneMatchKey{}, etag)
+}
+
+// setIfNoneMatch sets the If-None-Match header for the entity tag carried by ctx, if any
+func setIfNoneMatch(ctx context.Context, header http.Header) {
+	if etag, ok := ctx.Value(ifNoneMatchKey{}).(string); ok {
+		header.Set("If-None-Match", etag)
+	}
+}
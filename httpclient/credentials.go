@@ -0,0 +1,42 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+)
+
+// Headers carrying the kube credentials of a request
+const (
+	HeaderKubeToken     = "X-Kube-Token"
+	HeaderKubeAPIServer = "X-Kube-Api-Server"
+)
+
+type kubeCredentialsKey struct{}
+
+type kubeCredentials struct {
+	token     string
+	apiServer string
+}
+
+// WithKubeCredentials returns a context carrying the kube token and api server of a request.
+// They are sent in headers, which keeps them out of urls and request bodies
+func WithKubeCredentials(ctx context.Context, token string, apiServer string) context.Context {
+	if token == "" && apiServer == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, kubeCredentialsKey{}, kubeCredentials{token: token, apiServer: apiServer})
+}
+
+// setKubeCredentials sets the headers for the kube credentials carried by ctx, if any
+func setKubeCredentials(ctx context.Context, header http.Header) {
+	credentials, ok := ctx.Value(kubeCredentialsKey{}).(kubeCredentials)
+	if !ok {
+		return
+	}
+	if credentials.token != "" {
+		header.Set(HeaderKubeToken, credentials.token)
+	}
+	if credentials.apiServer != "" {
+		header.Set(HeaderKubeAPIServer, credentials.apiServer)
+	}
+}
@@ -0,0 +1,137 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/config"
+	"github.com/gojekfarm/albatross-client-go/logger"
+)
+
+// host is a single albatross replica and its health
+type host struct {
+	base           *url.URL
+	unhealthyUntil time.Time
+}
+
+// hostPool picks the host for each request attempt and tracks the health of all hosts.
+// A host is marked unhealthy on transport errors and 5xx responses, and is skipped
+// until the reprobe interval has passed.
+type hostPool struct {
+	mu     sync.Mutex
+	hosts  []*host
+	policy *config.HostPolicy
+	next   int
+	now    func() time.Time
+	logger logger.Logger
+}
+
+func newHostPool(hosts []string, policy *config.HostPolicy, logger logger.Logger) (*hostPool, error) {
+	if policy == nil {
+		policy = config.DefaultHostPolicy()
+	}
+
+	pool := &hostPool{
+		policy: policy,
+		now:    time.Now,
+		logger: logger,
+	}
+	for _, h := range hosts {
+		base, err := url.ParseRequestURI(h)
+		if err != nil {
+			return nil, err
+		}
+		pool.hosts = append(pool.hosts, &host{base: base})
+	}
+	return pool, nil
+}
+
+// pick returns the host for the next attempt. If all hosts are unhealthy,
+// the one which is due for a reprobe the earliest is returned
+func (p *hostPool) pick() *host {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	start := 0
+	if p.policy.Strategy == config.RoundRobin {
+		start = p.next
+		p.next = (p.next + 1) % len(p.hosts)
+	}
+
+	var fallback *host
+	for i := 0; i < len(p.hosts); i++ {
+		h := p.hosts[(start+i)%len(p.hosts)]
+		if !now.Before(h.unhealthyUntil) {
+			return h
+		}
+		if fallback == nil || h.unhealthyUntil.Before(fallback.unhealthyUntil) {
+			fallback = h
+		}
+	}
+	return fallback
+}
+
+// healthy reports whether any host is healthy
+func (p *hostPool) healthy() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	for _, h := range p.hosts {
+		if !now.Before(h.unhealthyUntil) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve builds the url for a request against the host. The reference is
+// expected to hold only the path and query of the request. Escaped path segments are kept
+func (h *host) resolve(reference string) (string, error) {
+	ref, err := url.Parse(reference)
+	if err != nil {
+		return "", err
+	}
+	u := *h.base
+	u.RawPath = path.Join(strings.TrimRight(u.EscapedPath(), "/"), ref.EscapedPath())
+	if u.Path, err = url.PathUnescape(u.RawPath); err != nil {
+		return "", err
+	}
+	u.RawQuery = ref.RawQuery
+	return u.String(), nil
+}
+
+// skipUntil marks the host unhealthy until the time, unless it already is for longer
+func (p *hostPool) skipUntil(h *host, until time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if until.After(h.unhealthyUntil) {
+		h.unhealthyUntil = until
+	}
+}
+
+// observe updates the health of the host that served the request
+func (p *hostPool) observe(h *host, resp *http.Response, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	wasHealthy := h.unhealthyUntil.IsZero()
+	if err != nil || resp.StatusCode >= 500 {
+		h.unhealthyUntil = p.now().Add(p.policy.ReprobeInterval)
+		if wasHealthy {
+			p.logger.Errorf("marking albatross host %s unhealthy", h.base)
+		}
+		return
+	}
+
+	h.unhealthyUntil = time.Time{}
+	if !wasHealthy {
+		p.logger.Infof("marking albatross host %s healthy", h.base)
+	}
+}
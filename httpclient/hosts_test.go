@@ -0,0 +1,187 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/config"
+	"github.com/gojekfarm/albatross-client-go/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHostPool(t *testing.T, strategy config.Strategy, now *time.Time) *hostPool {
+	pool, err := newHostPool(
+		[]string{"http://albatross-a:8080", "http://albatross-b:8080", "http://albatross-c:8080/api"},
+		&config.HostPolicy{Strategy: strategy, ReprobeInterval: time.Minute},
+		&logger.DefaultLogger{},
+	)
+	require.NoError(t, err)
+	pool.now = func() time.Time { return *now }
+	return pool
+}
+
+func pickHost(pool *hostPool) string {
+	return pool.pick().base.Host
+}
+
+func TestHostPoolRoundRobin(t *testing.T) {
+	now := time.Now()
+	pool := newTestHostPool(t, config.RoundRobin, &now)
+
+	assert.Equal(t, "albatross-a:8080", pickHost(pool))
+	assert.Equal(t, "albatross-b:8080", pickHost(pool))
+	assert.Equal(t, "albatross-c:8080", pickHost(pool))
+	assert.Equal(t, "albatross-a:8080", pickHost(pool))
+
+	pool.observe(pool.hosts[1], nil, errors.New("Network Error"))
+
+	assert.Equal(t, "albatross-c:8080", pickHost(pool))
+	assert.Equal(t, "albatross-c:8080", pickHost(pool))
+	assert.Equal(t, "albatross-a:8080", pickHost(pool))
+}
+
+func TestHostPoolFailover(t *testing.T) {
+	now := time.Now()
+	pool := newTestHostPool(t, config.Failover, &now)
+
+	assert.Equal(t, "albatross-a:8080", pickHost(pool))
+	assert.Equal(t, "albatross-a:8080", pickHost(pool))
+
+	pool.observe(pool.hosts[0], &http.Response{StatusCode: 503}, nil)
+	assert.Equal(t, "albatross-b:8080", pickHost(pool))
+
+	t.Run("When the reprobe interval has passed", func(t *testing.T) {
+		now = now.Add(2 * time.Minute)
+		assert.Equal(t, "albatross-a:8080", pickHost(pool))
+
+		pool.observe(pool.hosts[0], &http.Response{StatusCode: 200}, nil)
+		assert.True(t, pool.hosts[0].unhealthyUntil.IsZero())
+	})
+
+	t.Run("When all hosts are unhealthy", func(t *testing.T) {
+		pool.observe(pool.hosts[1], nil, errors.New("Network Error"))
+		now = now.Add(time.Second)
+		pool.observe(pool.hosts[0], nil, errors.New("Network Error"))
+		pool.observe(pool.hosts[2], nil, errors.New("Network Error"))
+
+		assert.Equal(t, "albatross-b:8080", pickHost(pool))
+	})
+}
+
+func TestHostPoolKeepsHealthPerBasePath(t *testing.T) {
+	now := time.Now()
+	pool, err := newHostPool(
+		[]string{"http://albatross:8080/a", "http://albatross:8080/b"},
+		&config.HostPolicy{Strategy: config.Failover, ReprobeInterval: time.Minute},
+		&logger.DefaultLogger{},
+	)
+	require.NoError(t, err)
+	pool.now = func() time.Time { return now }
+
+	pool.observe(pool.hosts[1], nil, errors.New("Network Error"))
+
+	assert.True(t, pool.hosts[0].unhealthyUntil.IsZero())
+	assert.Equal(t, "/a", pool.pick().base.Path)
+
+	pool.observe(pool.hosts[1], &http.Response{StatusCode: 200}, nil)
+	pool.observe(pool.hosts[0], &http.Response{StatusCode: 503}, nil)
+
+	assert.True(t, pool.hosts[1].unhealthyUntil.IsZero())
+	assert.Equal(t, "/b", pool.pick().base.Path)
+}
+
+func TestHostResolve(t *testing.T) {
+	base, _ := url.ParseRequestURI("http://albatross:8080/api/")
+	h := &host{base: base}
+
+	u, err := h.resolve("/clusters/staging/releases?deployed=true")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "http://albatross:8080/api/clusters/staging/releases?deployed=true", u)
+
+	u, err = h.resolve("/repositories/my%20charts%3Fv=1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "http://albatross:8080/api/repositories/my%20charts%3Fv=1", u)
+}
+
+func TestHttpClientFailsOverToAnotherHostOnRetry(t *testing.T) {
+	now := time.Now()
+	mc := new(mockClient)
+	response := &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("ab"))),
+	}
+	mc.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.URL.String() == "http://albatross-a:8080/clusters/staging/releases"
+	})).Return(&http.Response{}, errors.New("Network Error")).Once()
+	mc.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.URL.String() == "http://albatross-b:8080/clusters/staging/releases"
+	})).Return(response, nil).Once()
+	client := &Client{
+		client: mc,
+		retry:  &config.Retry{RetryCount: 1, Backoff: time.Millisecond},
+		logger: &logger.DefaultLogger{},
+		hosts:  newTestHostPool(t, config.Failover, &now),
+	}
+
+	resp, data, err := client.Send(context.Background(), "/clusters/staging/releases", "GET", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, []byte("ab"), data)
+	mc.AssertExpectations(t)
+}
+
+func TestHttpClientFailsOverOnceWithoutRetry(t *testing.T) {
+	now := time.Now()
+	mc := new(mockClient)
+	mc.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.URL.Host == "albatross-a:8080"
+	})).Return(&http.Response{
+		StatusCode: 503,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("unavailable"))),
+	}, nil).Once()
+	mc.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		body, _ := ioutil.ReadAll(req.Body)
+		return req.URL.Host == "albatross-b:8080" && string(body) == `{"name":"api"}`
+	})).Return(&http.Response{}, errors.New("Network Error")).Once()
+	client := &Client{
+		client: mc,
+		logger: &logger.DefaultLogger{},
+		hosts:  newTestHostPool(t, config.Failover, &now),
+	}
+
+	_, _, err := client.Send(context.Background(), "/clusters/staging/releases", "POST", bytes.NewBufferString(`{"name":"api"}`))
+
+	assert.EqualError(t, err, "Network Error")
+	assert.Equal(t, "albatross-c:8080", pickHost(client.hosts))
+	mc.AssertExpectations(t)
+}
+
+func TestHttpClientKeepsHostHealthyWhenTheRequestIsCancelled(t *testing.T) {
+	now := time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	mc := new(mockClient)
+	mc.On("Do", mock.Anything).Run(func(mock.Arguments) { cancel() }).Return(&http.Response{}, context.Canceled).Once()
+	client := &Client{
+		client: mc,
+		logger: &logger.DefaultLogger{},
+		hosts:  newTestHostPool(t, config.Failover, &now),
+	}
+
+	_, _, err := client.Send(ctx, "/clusters/staging/releases", "GET", nil)
+
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, client.hosts.hosts[0].unhealthyUntil.IsZero())
+	assert.Equal(t, "albatross-a:8080", pickHost(client.hosts))
+	mc.AssertExpectations(t)
+}
@@ -0,0 +1,142 @@
+package httpclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/config"
+)
+
+type kubeContextKey struct{}
+
+// WithKubeContext returns a context carrying the kube context a request acts on.
+// It is used to key the rate limits when they are kept per kube context
+func WithKubeContext(ctx context.Context, kubeContext string) context.Context {
+	return context.WithValue(ctx, kubeContextKey{}, kubeContext)
+}
+
+func kubeContextFrom(ctx context.Context) string {
+	kubeContext, _ := ctx.Value(kubeContextKey{}).(string)
+	return kubeContext
+}
+
+// limiters keeps the limiter of the client, or one for each kube context
+type limiters struct {
+	mu     sync.Mutex
+	policy *config.RateLimit
+	byKey  map[string]*limiter
+	now    func() time.Time
+}
+
+// limiter combines a token bucket for the request rate with a semaphore for
+// the requests in flight. Either can be disabled
+type limiter struct {
+	bucket   *tokenBucket
+	inFlight chan struct{}
+}
+
+// tokenBucket refills at rate tokens per second up to burst tokens.
+// Tokens can go negative, in which case they are reserved by waiting requests
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newLimiters(policy *config.RateLimit) *limiters {
+	return &limiters{
+		policy: policy,
+		byKey:  map[string]*limiter{},
+		now:    time.Now,
+	}
+}
+
+// get returns the limiter for the kube context, or the shared limiter if
+// limits are not kept per kube context
+func (l *limiters) get(kubeContext string) *limiter {
+	if !l.policy.PerKubeContext {
+		kubeContext = ""
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lim, ok := l.byKey[kubeContext]
+	if !ok {
+		lim = &limiter{}
+		if l.policy.RequestsPerSecond > 0 {
+			burst := float64(l.policy.Burst)
+			if burst <= 0 {
+				burst = 1
+			}
+			lim.bucket = &tokenBucket{
+				rate:   l.policy.RequestsPerSecond,
+				burst:  burst,
+				tokens: burst,
+				last:   l.now(),
+				now:    l.now,
+			}
+		}
+		if l.policy.MaxInFlight > 0 {
+			lim.inFlight = make(chan struct{}, l.policy.MaxInFlight)
+		}
+		l.byKey[kubeContext] = lim
+	}
+	return lim
+}
+
+// acquire waits for a token and a free in flight slot. The returned func must be
+// called once the request is done to release the slot
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l.bucket != nil {
+		if err := l.bucket.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.inFlight == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case l.inFlight <- struct{}{}:
+		return func() { <-l.inFlight }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// wait takes a token, waiting until one is available or ctx is done.
+// The token is given back if ctx is done before it became available
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
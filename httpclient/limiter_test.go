@@ -0,0 +1,96 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/config"
+	"github.com/gojekfarm/albatross-client-go/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucketWait(t *testing.T) {
+	now := time.Now()
+	bucket := &tokenBucket{
+		rate:   10,
+		burst:  2,
+		tokens: 2,
+		last:   now,
+		now:    func() time.Time { return now },
+	}
+
+	assert.NoError(t, bucket.wait(context.Background()))
+	assert.NoError(t, bucket.wait(context.Background()))
+
+	t.Run("When the bucket is empty it waits for a token", func(t *testing.T) {
+		start := time.Now()
+		assert.NoError(t, bucket.wait(context.Background()))
+		assert.True(t, time.Since(start) >= 90*time.Millisecond)
+	})
+
+	t.Run("When the context is done while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := bucket.wait(ctx)
+
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Equal(t, float64(-1), bucket.tokens, "the token reserved for the cancelled request is given back")
+	})
+}
+
+func TestLimiterMaxInFlight(t *testing.T) {
+	lim := newLimiters(&config.RateLimit{MaxInFlight: 1}).get("")
+
+	release, err := lim.acquire(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = lim.acquire(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	release()
+	release, err = lim.acquire(context.Background())
+	assert.NoError(t, err)
+	release()
+}
+
+func TestLimitersPerKubeContext(t *testing.T) {
+	shared := newLimiters(&config.RateLimit{MaxInFlight: 1})
+	assert.Same(t, shared.get("staging"), shared.get("production"))
+
+	perKubeContext := newLimiters(&config.RateLimit{MaxInFlight: 1, PerKubeContext: true})
+	assert.NotSame(t, perKubeContext.get("staging"), perKubeContext.get("production"))
+	assert.Same(t, perKubeContext.get("staging"), perKubeContext.get("staging"))
+}
+
+func TestHttpClientSendWaitsForLimits(t *testing.T) {
+	mc := new(mockClient)
+	mc.On("Do", mock.Anything).Return(&http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("ab"))),
+	}, nil).Once()
+	client := &Client{
+		client:   mc,
+		logger:   &logger.DefaultLogger{},
+		limiters: newLimiters(&config.RateLimit{RequestsPerSecond: 0.001, PerKubeContext: true}),
+	}
+	ctx := WithKubeContext(context.Background(), "staging")
+
+	_, _, err := client.Send(ctx, "http://localhost:444", "GET", nil)
+	require.NoError(t, err)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, _, err = client.Send(timeoutCtx, "http://localhost:444", "GET", nil)
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+	mc.AssertExpectations(t)
+}
@@ -0,0 +1,193 @@
+// Package jsonschema validates chart values against a values.schema.json, as helm does
+// before installing a chart. Validation is done by github.com/xeipuuv/gojsonschema,
+// the validator helm uses.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Schema is a parsed JSON schema
+type Schema struct {
+	schema *gojsonschema.Schema
+}
+
+// ValidationError is a single problem with a value, at a path such as "image.tag" or "ports.0"
+type ValidationError struct {
+	Path    string
+	Message string
+
+	// Keyword is the kind of problem, such as "required" or "invalid_type"
+	Keyword string
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// Errors holds all problems found while validating a value
+type Errors []ValidationError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Parse parses a JSON schema document. Schemas whose local references form a cycle
+// that never descends into the value, such as {"$ref": "#"}, are rejected, as they
+// would never stop validating
+func Parse(data []byte) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("Error parsing the schema: %s", err)
+	}
+	if err := checkCycles(root); err != nil {
+		return nil, fmt.Errorf("Error parsing the schema: %s", err)
+	}
+
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing the schema: %s", err)
+	}
+	return &Schema{schema: schema}, nil
+}
+
+// LoadFile reads and parses a JSON schema file, such as the values.schema.json of a chart
+func LoadFile(path string) (*Schema, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the schema: %s", err)
+	}
+	return Parse(data)
+}
+
+// Validate checks the value against the schema and returns Errors with all problems,
+// sorted by path. The value is converted to JSON first, so any value that can be
+// marshaled can be validated
+func (s *Schema) Validate(value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("Error converting the value to json: %s", err)
+	}
+	result, err := s.schema.Validate(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return fmt.Errorf("Error validating the value: %s", err)
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	errs := make(Errors, 0, len(result.Errors()))
+	for _, resultErr := range result.Errors() {
+		errs = append(errs, ValidationError{Path: resultErr.Field(), Message: resultErr.Description(), Keyword: resultErr.Type()})
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return errs
+}
+
+// inPlaceKeywords hold subschemas that apply to the value itself, rather than to its items or properties
+var inPlaceKeywords = []string{"allOf", "anyOf", "oneOf", "not", "if", "then", "else"}
+
+// checkCycles returns an error if a subschema applies to a value through local references
+// and in place subschemas only, and so reaches itself without descending into the value
+func checkCycles(root interface{}) error {
+	const visiting, done = 1, 2
+	state := map[string]int{}
+
+	var visit func(pointer string) error
+	visit = func(pointer string) error {
+		switch state[pointer] {
+		case visiting:
+			return fmt.Errorf("circular reference to \"#%s\"", pointer)
+		case done:
+			return nil
+		}
+		state[pointer] = visiting
+
+		if object, ok := lookup(root, pointer).(map[string]interface{}); ok {
+			if ref, ok := object["$ref"].(string); ok && strings.HasPrefix(ref, "#") {
+				if err := visit(strings.TrimPrefix(ref, "#")); err != nil {
+					return err
+				}
+			}
+			for _, keyword := range inPlaceKeywords {
+				switch sub := object[keyword].(type) {
+				case map[string]interface{}:
+					if err := visit(pointer + "/" + keyword); err != nil {
+						return err
+					}
+				case []interface{}:
+					for i := range sub {
+						if err := visit(pointer + "/" + keyword + "/" + strconv.Itoa(i)); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+
+		state[pointer] = done
+		return nil
+	}
+
+	var walk func(node interface{}, pointer string) error
+	walk = func(node interface{}, pointer string) error {
+		switch node := node.(type) {
+		case map[string]interface{}:
+			if err := visit(pointer); err != nil {
+				return err
+			}
+			for key, child := range node {
+				if err := walk(child, pointer+"/"+escape(key)); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			for i, child := range node {
+				if err := walk(child, pointer+"/"+strconv.Itoa(i)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk(root, "")
+}
+
+// lookup returns the node a JSON pointer such as "/definitions/port" points to, or nil
+func lookup(root interface{}, pointer string) interface{} {
+	current := root
+	if pointer == "" {
+		return current
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[token]
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil
+			}
+			current = node[i]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+func escape(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
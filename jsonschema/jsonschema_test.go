@@ -0,0 +1,144 @@
+package jsonschema
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const valuesSchema = `{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"type": "object",
+	"required": ["image"],
+	"additionalProperties": false,
+	"properties": {
+		"replicas": {"type": "integer", "minimum": 1, "maximum": 10},
+		"image": {
+			"type": "object",
+			"required": ["repository"],
+			"properties": {
+				"repository": {"type": "string", "minLength": 1},
+				"tag": {"type": "string", "pattern": "^v[0-9]+"},
+				"pullPolicy": {"enum": ["Always", "IfNotPresent", "Never"]}
+			}
+		},
+		"ports": {"type": "array", "items": {"$ref": "#/definitions/port"}, "uniqueItems": true},
+		"resources": {"type": ["object", "null"]},
+		"ratio": {"type": "number", "multipleOf": 0.25, "exclusiveMaximum": 1}
+	},
+	"definitions": {
+		"port": {
+			"type": "object",
+			"required": ["name", "port"],
+			"properties": {
+				"name": {"type": "string"},
+				"port": {"type": "integer", "minimum": 1, "maximum": 65535}
+			}
+		}
+	}
+}`
+
+func mustParse(t *testing.T, schema string) *Schema {
+	s, err := Parse([]byte(schema))
+	require.NoError(t, err)
+	return s
+}
+
+func TestValidateValidValues(t *testing.T) {
+	s := mustParse(t, valuesSchema)
+
+	err := s.Validate(map[string]interface{}{
+		"replicas":  3,
+		"image":     map[string]interface{}{"repository": "api", "tag": "v2", "pullPolicy": "Always"},
+		"ports":     []interface{}{map[string]interface{}{"name": "http", "port": 8080}},
+		"resources": nil,
+		"ratio":     0.75,
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestValidateReturnsAllErrorsWithPaths(t *testing.T) {
+	s := mustParse(t, valuesSchema)
+
+	err := s.Validate(map[string]interface{}{
+		"replicas": 1.5,
+		"image":    map[string]interface{}{"tag": "latest", "pullPolicy": "Sometimes"},
+		"ports": []interface{}{
+			map[string]interface{}{"name": "http", "port": 70000},
+			map[string]interface{}{"name": "grpc"},
+		},
+		"ratio":   0.3,
+		"unknown": true,
+	})
+
+	var errs Errors
+	require.True(t, errors.As(err, &errs))
+	assert.Equal(t, Errors{
+		{Path: "(root)", Message: "Additional property unknown is not allowed", Keyword: "additional_property_not_allowed"},
+		{Path: "image", Message: "repository is required", Keyword: "required"},
+		{Path: "image.pullPolicy", Message: `image.pullPolicy must be one of the following: "Always", "IfNotPresent", "Never"`, Keyword: "enum"},
+		{Path: "image.tag", Message: "Does not match pattern '^v[0-9]+'", Keyword: "pattern"},
+		{Path: "ports.0.port", Message: "Must be less than or equal to 65535", Keyword: "number_lte"},
+		{Path: "ports.1", Message: "port is required", Keyword: "required"},
+		{Path: "ratio", Message: "Must be a multiple of 0.25", Keyword: "multiple_of"},
+		{Path: "replicas", Message: "Invalid type. Expected: integer, given: number", Keyword: "invalid_type"},
+	}, errs)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse([]byte(`{"type": `))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte(`{"type": "unknown"}`))
+	assert.Error(t, err)
+}
+
+func TestParseRejectsCircularReferences(t *testing.T) {
+	testCases := []struct {
+		name   string
+		schema string
+	}{
+		{"self reference", `{"$ref": "#"}`},
+		{"reference chain", `{"$ref": "#/definitions/a", "definitions": {"a": {"$ref": "#/definitions/b"}, "b": {"$ref": "#/definitions/a"}}}`},
+		{"reference in all of", `{"definitions": {"a": {"allOf": [{"$ref": "#/definitions/a"}]}}, "properties": {"x": {"$ref": "#/definitions/a"}}}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.schema))
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "circular reference")
+		})
+	}
+}
+
+func TestValidateRecursiveSchemas(t *testing.T) {
+	s := mustParse(t, `{
+		"$ref": "#/definitions/node",
+		"definitions": {"node": {"type": "object", "properties": {"child": {"$ref": "#/definitions/node"}}}}
+	}`)
+
+	err := s.Validate(map[string]interface{}{"child": map[string]interface{}{"child": 1}})
+
+	assert.EqualError(t, err, "child.child: Invalid type. Expected: object, given: integer")
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "albatross-schema")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "values.schema.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(valuesSchema), 0600))
+
+	s, err := LoadFile(path)
+
+	require.NoError(t, err)
+	assert.EqualError(t, s.Validate(map[string]interface{}{}), "(root): image is required")
+}
@@ -0,0 +1,73 @@
+package kubeconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// defaultExecAPIVersion is sent to exec plugins that do not configure an api version
+const defaultExecAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+// execCredential is the json schema exchanged with exec credential plugins
+type execCredential struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		Interactive bool `json:"interactive"`
+	} `json:"spec"`
+	Status *struct {
+		Token string `json:"token"`
+	} `json:"status,omitempty"`
+}
+
+// token runs the plugin and returns the token of the ExecCredential it prints.
+// The plugin gets the environment of the process, the env of its config, and the
+// request in KUBERNETES_EXEC_INFO
+func (e *ExecConfig) token(ctx context.Context) (string, error) {
+	if e.Command == "" {
+		return "", errors.New("exec credential plugin has no command")
+	}
+
+	apiVersion := e.APIVersion
+	if apiVersion == "" {
+		apiVersion = defaultExecAPIVersion
+	}
+	info, err := json.Marshal(&execCredential{APIVersion: apiVersion, Kind: "ExecCredential"})
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.CommandContext(ctx, e.Command, e.Args...)
+	cmd.Env = append(os.Environ(), "KUBERNETES_EXEC_INFO="+string(info))
+	for _, env := range e.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("exec credential plugin %s failed: %s: %s", e.Command, err, msg)
+		}
+		return "", fmt.Errorf("exec credential plugin %s failed: %s", e.Command, err)
+	}
+
+	var credential execCredential
+	if err := json.Unmarshal(stdout.Bytes(), &credential); err != nil {
+		return "", fmt.Errorf("Error parsing the output of exec credential plugin %s: %s", e.Command, err)
+	}
+	if credential.Kind != "ExecCredential" {
+		return "", fmt.Errorf("exec credential plugin %s returned kind %q, expected ExecCredential", e.Command, credential.Kind)
+	}
+	if credential.Status == nil || credential.Status.Token == "" {
+		return "", fmt.Errorf("exec credential plugin %s returned no token", e.Command)
+	}
+	return credential.Status.Token, nil
+}
@@ -0,0 +1,212 @@
+// Package kubeconfig reads kubectl config files and turns the credentials of a
+// context into the common flags of the albatross apis, so that releases are
+// managed with the cluster identity of the caller.
+package kubeconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvKubeconfig holds the list of kubeconfig files to merge, separated like PATH
+const EnvKubeconfig = "KUBECONFIG"
+
+// Config is the merged content of one or more kubeconfig files
+type Config struct {
+	CurrentContext string
+	Clusters       map[string]Cluster
+	Contexts       map[string]Context
+	Users          map[string]User
+}
+
+// Cluster is the address of a kubernetes api server
+type Cluster struct {
+	Server string `yaml:"server"`
+}
+
+// Context binds a cluster, a user and a default namespace
+type Context struct {
+	Cluster   string `yaml:"cluster"`
+	User      string `yaml:"user"`
+	Namespace string `yaml:"namespace"`
+}
+
+// User holds the credentials of a user. Only token based credentials are supported
+type User struct {
+	Token     string      `yaml:"token"`
+	TokenFile string      `yaml:"tokenFile"`
+	Exec      *ExecConfig `yaml:"exec"`
+}
+
+// ExecConfig is an exec credential plugin, a command that prints an ExecCredential
+// with a token, such as the ones of managed kubernetes offerings
+type ExecConfig struct {
+	APIVersion string    `yaml:"apiVersion"`
+	Command    string    `yaml:"command"`
+	Args       []string  `yaml:"args"`
+	Env        []ExecEnv `yaml:"env"`
+}
+
+// ExecEnv is an environment variable set for an exec credential plugin
+type ExecEnv struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// file is the yaml schema of a kubeconfig file
+type file struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string  `yaml:"name"`
+		Cluster Cluster `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string  `yaml:"name"`
+		Context Context `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User User   `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// LoadDefault loads the files listed in KUBECONFIG, or ~/.kube/config if it is not set
+func LoadDefault() (*Config, error) {
+	if paths := os.Getenv(EnvKubeconfig); paths != "" {
+		return Load(filepath.SplitList(paths)...)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("Error finding the kubeconfig: %s", err)
+	}
+	return Load(filepath.Join(home, ".kube", "config"))
+}
+
+// Load reads and merges kubeconfig files the way kubectl does: the first file to
+// define a cluster, context or user, or the current context, wins. Missing files are
+// skipped as long as at least one file exists. Relative token files are resolved
+// against the directory of the file they are defined in
+func Load(paths ...string) (*Config, error) {
+	cfg := &Config{
+		Clusters: map[string]Cluster{},
+		Contexts: map[string]Context{},
+		Users:    map[string]User{},
+	}
+
+	found := false
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error reading kubeconfig %s: %s", path, err)
+		}
+		found = true
+
+		var f file
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("Error parsing kubeconfig %s: %s", path, err)
+		}
+		cfg.merge(f, filepath.Dir(path))
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no kubeconfig found in %s", strings.Join(paths, ", "))
+	}
+	return cfg, nil
+}
+
+func (c *Config) merge(f file, dir string) {
+	if c.CurrentContext == "" {
+		c.CurrentContext = f.CurrentContext
+	}
+	for _, cluster := range f.Clusters {
+		if _, ok := c.Clusters[cluster.Name]; !ok {
+			c.Clusters[cluster.Name] = cluster.Cluster
+		}
+	}
+	for _, kubeContext := range f.Contexts {
+		if _, ok := c.Contexts[kubeContext.Name]; !ok {
+			c.Contexts[kubeContext.Name] = kubeContext.Context
+		}
+	}
+	for _, user := range f.Users {
+		if _, ok := c.Users[user.Name]; ok {
+			continue
+		}
+		if user.User.TokenFile != "" && !filepath.IsAbs(user.User.TokenFile) {
+			user.User.TokenFile = filepath.Join(dir, user.User.TokenFile)
+		}
+		c.Users[user.Name] = user.User
+	}
+}
+
+// CommonFlags returns the flags acting on the cluster of a context with the credentials
+// of its user. The current context is used if contextName is empty. The kube context
+// of the flags is the name of the context, which must match the cluster name known to
+// the albatross server. Exec credential plugins are run with ctx
+func (c *Config) CommonFlags(ctx context.Context, contextName string) (flags.CommonFlags, error) {
+	if contextName == "" {
+		contextName = c.CurrentContext
+	}
+	if contextName == "" {
+		return flags.CommonFlags{}, errors.New("no context given and no current context set in the kubeconfig")
+	}
+
+	kubeContext, ok := c.Contexts[contextName]
+	if !ok {
+		return flags.CommonFlags{}, fmt.Errorf("context %q not found in the kubeconfig", contextName)
+	}
+	cluster, ok := c.Clusters[kubeContext.Cluster]
+	if !ok {
+		return flags.CommonFlags{}, fmt.Errorf("cluster %q of context %q not found in the kubeconfig", kubeContext.Cluster, contextName)
+	}
+	user, ok := c.Users[kubeContext.User]
+	if !ok {
+		return flags.CommonFlags{}, fmt.Errorf("user %q of context %q not found in the kubeconfig", kubeContext.User, contextName)
+	}
+
+	token, err := user.token(ctx)
+	if err != nil {
+		return flags.CommonFlags{}, fmt.Errorf("Error getting the token of user %q: %s", kubeContext.User, err)
+	}
+
+	return flags.CommonFlags{
+		KubeContext:   contextName,
+		Namespace:     kubeContext.Namespace,
+		KubeToken:     flags.Secret(token),
+		KubeAPIServer: cluster.Server,
+	}, nil
+}
+
+// token returns the token of the user, in order of precedence from the token,
+// the token file or the exec credential plugin
+func (u User) token(ctx context.Context) (string, error) {
+	switch {
+	case u.Token != "":
+		return u.Token, nil
+	case u.TokenFile != "":
+		data, err := ioutil.ReadFile(u.TokenFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	case u.Exec != nil:
+		return u.Exec.token(ctx)
+	default:
+		return "", errors.New("no token, token file or exec credential plugin configured")
+	}
+}
@@ -0,0 +1,207 @@
+package kubeconfig
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const primaryConfig = `
+apiVersion: v1
+kind: Config
+current-context: staging
+clusters:
+  - name: staging
+    cluster:
+      server: https://kube.staging:6443
+contexts:
+  - name: staging
+    context:
+      cluster: staging
+      user: deployer
+      namespace: apps
+users:
+  - name: deployer
+    user:
+      token: staging-token
+`
+
+const secondaryConfig = `
+apiVersion: v1
+kind: Config
+current-context: production
+clusters:
+  - name: staging
+    cluster:
+      server: https://ignored:6443
+  - name: production
+    cluster:
+      server: https://kube.production:6443
+contexts:
+  - name: production
+    context:
+      cluster: production
+      user: file-user
+  - name: gke
+    context:
+      cluster: production
+      user: exec-user
+users:
+  - name: deployer
+    user:
+      token: ignored
+  - name: file-user
+    user:
+      tokenFile: tokens/production
+  - name: exec-user
+    user:
+      exec:
+        apiVersion: client.authentication.k8s.io/v1beta1
+        command: ./credential-plugin
+        env:
+          - name: PLUGIN_TOKEN
+            value: exec-token
+`
+
+func writeFile(t *testing.T, dir string, name string, content string, perm os.FileMode) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), perm))
+	return path
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func loadTestConfig(t *testing.T) *Config {
+	dir := tempDir(t)
+	primary := writeFile(t, dir, "primary", primaryConfig, 0600)
+	secondary := writeFile(t, dir, "secondary/config", secondaryConfig, 0600)
+	writeFile(t, dir, "secondary/tokens/production", "production-token\n", 0600)
+
+	cfg, err := Load(primary, filepath.Join(dir, "missing"), secondary)
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestLoadMergesFiles(t *testing.T) {
+	cfg := loadTestConfig(t)
+
+	assert.Equal(t, "staging", cfg.CurrentContext)
+	assert.Equal(t, "https://kube.staging:6443", cfg.Clusters["staging"].Server)
+	assert.Equal(t, "https://kube.production:6443", cfg.Clusters["production"].Server)
+	assert.Equal(t, "staging-token", cfg.Users["deployer"].Token)
+	assert.Len(t, cfg.Contexts, 3)
+	assert.True(t, filepath.IsAbs(cfg.Users["file-user"].TokenFile))
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := tempDir(t)
+
+	_, err := Load(filepath.Join(dir, "missing"))
+	assert.EqualError(t, err, "no kubeconfig found in "+filepath.Join(dir, "missing"))
+
+	_, err = Load(writeFile(t, dir, "invalid", "clusters: {", 0600))
+	assert.Error(t, err)
+}
+
+func TestLoadDefaultReadsKubeconfigEnv(t *testing.T) {
+	dir := tempDir(t)
+	primary := writeFile(t, dir, "primary", primaryConfig, 0600)
+	secondary := writeFile(t, dir, "secondary", secondaryConfig, 0600)
+	previous, set := os.LookupEnv(EnvKubeconfig)
+	os.Setenv(EnvKubeconfig, primary+string(filepath.ListSeparator)+secondary)
+	t.Cleanup(func() {
+		if set {
+			os.Setenv(EnvKubeconfig, previous)
+		} else {
+			os.Unsetenv(EnvKubeconfig)
+		}
+	})
+
+	cfg, err := LoadDefault()
+
+	require.NoError(t, err)
+	assert.Equal(t, "staging", cfg.CurrentContext)
+	assert.Contains(t, cfg.Contexts, "gke")
+}
+
+func TestCommonFlags(t *testing.T) {
+	cfg := loadTestConfig(t)
+
+	t.Run("Uses the current context and a token", func(t *testing.T) {
+		fl, err := cfg.CommonFlags(context.Background(), "")
+
+		require.NoError(t, err)
+		assert.Equal(t, flags.CommonFlags{
+			KubeContext:   "staging",
+			Namespace:     "apps",
+			KubeToken:     "staging-token",
+			KubeAPIServer: "https://kube.staging:6443",
+		}, fl)
+	})
+
+	t.Run("Reads the token file", func(t *testing.T) {
+		fl, err := cfg.CommonFlags(context.Background(), "production")
+
+		require.NoError(t, err)
+		assert.Equal(t, "production-token", fl.KubeToken.Reveal())
+		assert.Equal(t, "https://kube.production:6443", fl.KubeAPIServer)
+		assert.Empty(t, fl.Namespace)
+	})
+
+	t.Run("Fails for unknown contexts", func(t *testing.T) {
+		_, err := cfg.CommonFlags(context.Background(), "unknown")
+
+		assert.EqualError(t, err, `context "unknown" not found in the kubeconfig`)
+	})
+
+	t.Run("Fails for users without a token", func(t *testing.T) {
+		cfg.Users["cert-user"] = User{}
+		cfg.Contexts["cert"] = Context{Cluster: "staging", User: "cert-user"}
+
+		_, err := cfg.CommonFlags(context.Background(), "cert")
+
+		assert.EqualError(t, err, `Error getting the token of user "cert-user": no token, token file or exec credential plugin configured`)
+	})
+}
+
+func TestCommonFlagsRunsExecPlugin(t *testing.T) {
+	dir := tempDir(t)
+	plugin := writeFile(t, dir, "credential-plugin", `#!/bin/sh
+case "$KUBERNETES_EXEC_INFO" in
+  *ExecCredential*) ;;
+  *) echo "missing exec info" >&2; exit 1 ;;
+esac
+echo "{\"apiVersion\":\"client.authentication.k8s.io/v1beta1\",\"kind\":\"ExecCredential\",\"status\":{\"token\":\"$PLUGIN_TOKEN\"}}"
+`, 0700)
+	cfg := loadTestConfig(t)
+	user := cfg.Users["exec-user"]
+	user.Exec.Command = plugin
+	cfg.Users["exec-user"] = user
+
+	fl, err := cfg.CommonFlags(context.Background(), "gke")
+
+	require.NoError(t, err)
+	assert.Equal(t, "exec-token", fl.KubeToken.Reveal())
+
+	t.Run("Returns the error of the plugin", func(t *testing.T) {
+		user.Exec.Command = writeFile(t, dir, "failing-plugin", "#!/bin/sh\necho 'not logged in' >&2\nexit 1\n", 0700)
+		cfg.Users["exec-user"] = user
+
+		_, err := cfg.CommonFlags(context.Background(), "gke")
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exit status 1: not logged in")
+	})
+}
@@ -0,0 +1,66 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a single kubernetes object of a rendered manifest
+type Document struct {
+	// Source is the template the object was rendered from, if the manifest has
+	// helm's "# Source:" comments
+	Source string
+
+	APIVersion string
+	Kind       string
+	Name       string
+	Namespace  string
+
+	// Content is the yaml of the object
+	Content string
+}
+
+var separator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// object is the part of a kubernetes object needed to identify it
+type object struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+}
+
+// Parse splits a rendered manifest into its documents. Empty documents,
+// such as templates that render to nothing, are left out
+func Parse(manifest string) ([]Document, error) {
+	var docs []Document
+	for _, content := range separator.Split(manifest, -1) {
+		doc := Document{Content: strings.TrimSpace(content)}
+		for _, line := range strings.Split(doc.Content, "\n") {
+			if strings.HasPrefix(line, "# Source: ") {
+				doc.Source = strings.TrimPrefix(line, "# Source: ")
+				break
+			}
+		}
+
+		var obj object
+		if err := yaml.Unmarshal([]byte(content), &obj); err != nil {
+			return nil, fmt.Errorf("Error parsing manifest document %s: %s", doc.Source, err)
+		}
+		if obj.Kind == "" && obj.APIVersion == "" {
+			continue
+		}
+
+		doc.APIVersion = obj.APIVersion
+		doc.Kind = obj.Kind
+		doc.Name = obj.Metadata.Name
+		doc.Namespace = obj.Metadata.Namespace
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
@@ -0,0 +1,51 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testManifest = `---
+# Source: api/templates/serviceaccount.yaml
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: api
+  namespace: apps
+---
+# Source: api/templates/empty.yaml
+---
+# Source: api/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  replicas: 2
+`
+
+func TestParse(t *testing.T) {
+	docs, err := Parse(testManifest)
+
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, Document{
+		Source:     "api/templates/serviceaccount.yaml",
+		APIVersion: "v1",
+		Kind:       "ServiceAccount",
+		Name:       "api",
+		Namespace:  "apps",
+		Content:    "# Source: api/templates/serviceaccount.yaml\napiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: api\n  namespace: apps",
+	}, docs[0])
+	assert.Equal(t, "Deployment", docs[1].Kind)
+	assert.Equal(t, "apps/v1", docs[1].APIVersion)
+	assert.Equal(t, "", docs[1].Namespace)
+}
+
+func TestParseInvalidDocument(t *testing.T) {
+	_, err := Parse("# Source: api/templates/broken.yaml\nkind: [")
+
+	assert.Error(t, err)
+}
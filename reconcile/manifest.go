@@ -0,0 +1,144 @@
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is the desired state of releases, grouped by cluster and namespace
+type Manifest struct {
+	Targets []Target `yaml:"targets"`
+
+	// Decryptors decrypt encrypted values files before they are merged
+	Decryptors []secrets.Decryptor `yaml:"-"`
+
+	// dir is the directory values files are resolved against
+	dir string
+}
+
+// Target holds the desired releases of a single namespace in a cluster
+type Target struct {
+	KubeContext string `yaml:"kube_context"`
+	Namespace   string `yaml:"namespace"`
+
+	// Prune uninstalls releases in the namespace that are not part of the manifest
+	Prune bool `yaml:"prune"`
+
+	Releases []Release `yaml:"releases"`
+}
+
+// Release is the desired state of a single release
+type Release struct {
+	Name    string `yaml:"name"`
+	Chart   string `yaml:"chart"`
+	Version string `yaml:"version"`
+
+	// ValuesFiles are merged in order, followed by Values.
+	// Relative paths are resolved against the directory of the manifest
+	ValuesFiles []string   `yaml:"values_files"`
+	Values      api.Values `yaml:"values"`
+
+	// Installed defaults to true. Setting it to false uninstalls the release
+	Installed *bool `yaml:"installed"`
+
+	Flags ReleaseFlags `yaml:"flags"`
+}
+
+// ReleaseFlags are passed to the install and upgrade apis
+type ReleaseFlags struct {
+	DryRun  bool `yaml:"dry_run"`
+	Wait    bool `yaml:"wait"`
+	Atomic  bool `yaml:"atomic"`
+	Timeout int  `yaml:"timeout"`
+}
+
+// IsInstalled reports whether the release should be installed
+func (r Release) IsInstalled() bool {
+	return r.Installed == nil || *r.Installed
+}
+
+// LoadManifest reads and validates a manifest file
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the manifest: %s", err)
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("Error parsing the manifest %s: %s", path, err)
+	}
+	m.dir = filepath.Dir(path)
+
+	m = m.WithDefaults()
+	if err := m.Valid(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// WithDefaults returns a copy of the manifest with the default namespace set on targets without one
+func (m Manifest) WithDefaults() Manifest {
+	targets := make([]Target, len(m.Targets))
+	for i, target := range m.Targets {
+		if target.Namespace == "" {
+			target.Namespace = flags.DefaultNamespace
+		}
+		targets[i] = target
+	}
+	m.Targets = targets
+	return m
+}
+
+// Valid checks the manifest for missing fields and duplicate releases. It does not
+// change the manifest, defaults are set by WithDefaults. Targets without a namespace
+// are checked as if they were in the default namespace
+func (m Manifest) Valid() error {
+	seen := map[string]bool{}
+	for _, target := range m.WithDefaults().Targets {
+		if target.KubeContext == "" {
+			return errors.New("kube context is a required parameter for every target")
+		}
+
+		for _, rel := range target.Releases {
+			if rel.Name == "" {
+				return fmt.Errorf("release name cannot be empty in %s/%s", target.KubeContext, target.Namespace)
+			}
+			if err := flags.ValidReleaseName(rel.Name); err != nil {
+				return fmt.Errorf("%s in %s/%s", err, target.KubeContext, target.Namespace)
+			}
+			if rel.Chart == "" && rel.IsInstalled() {
+				return fmt.Errorf("chart cannot be empty for release %s in %s/%s", rel.Name, target.KubeContext, target.Namespace)
+			}
+			key := target.KubeContext + "/" + target.Namespace + "/" + rel.Name
+			if seen[key] {
+				return fmt.Errorf("duplicate release %s in %s/%s", rel.Name, target.KubeContext, target.Namespace)
+			}
+			seen[key] = true
+		}
+	}
+	return nil
+}
+
+// values merges the values files and inline values of a release
+func (m *Manifest) values(rel Release) (api.Values, error) {
+	values := api.Values{}
+	for _, file := range rel.ValuesFiles {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(m.dir, file)
+		}
+		fileValues, err := secrets.ReadValuesFile(file, m.Decryptors...)
+		if err != nil {
+			return nil, fmt.Errorf("%s for release %s", err, rel.Name)
+		}
+		values = values.Merge(fileValues)
+	}
+	return values.Merge(rel.Values), nil
+}
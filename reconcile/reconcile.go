@@ -0,0 +1,318 @@
+// Package reconcile compares the releases described in a manifest with the releases
+// deployed through albatross, and plans and applies the changes between them.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/gojekfarm/albatross-client-go/batch"
+	"github.com/gojekfarm/albatross-client-go/chart"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+)
+
+// ChangeType is the kind of change planned for a release
+type ChangeType string
+
+const (
+	Install   ChangeType = "install"
+	Upgrade   ChangeType = "upgrade"
+	Uninstall ChangeType = "uninstall"
+	Unchanged ChangeType = "unchanged"
+)
+
+// Change is the planned change for a single release
+type Change struct {
+	Type        ChangeType
+	KubeContext string
+	Namespace   string
+	Name        string
+	Chart       string
+	Version     string
+	Values      api.Values
+	Flags       ReleaseFlags
+
+	// Current is the deployed release, if there is one
+	Current *release.Release
+
+	// Reason explains why an upgrade is planned
+	Reason string
+}
+
+// Plan is the list of changes needed to reach the desired state
+type Plan struct {
+	Changes []Change
+}
+
+// HasChanges reports whether applying the plan would change anything
+func (p *Plan) HasChanges() bool {
+	for _, change := range p.Changes {
+		if change.Type != Unchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// Write prints the plan in a human readable form, grouped by cluster and namespace
+func (p *Plan) Write(w io.Writer) error {
+	counts := map[ChangeType]int{}
+	target := ""
+	for _, change := range p.Changes {
+		counts[change.Type]++
+		if t := change.KubeContext + "/" + change.Namespace; t != target {
+			target = t
+			if _, err := fmt.Fprintf(w, "%s:\n", target); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "  %s\n", change.describe()); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "Plan: %d to install, %d to upgrade, %d to uninstall, %d unchanged.\n",
+		counts[Install], counts[Upgrade], counts[Uninstall], counts[Unchanged])
+	return err
+}
+
+// String returns the human readable form of the plan
+func (p *Plan) String() string {
+	var b strings.Builder
+	_ = p.Write(&b)
+	return b.String()
+}
+
+func (c Change) describe() string {
+	desired := c.Chart
+	if c.Version != "" {
+		desired += " " + c.Version
+	}
+
+	switch c.Type {
+	case Install:
+		return fmt.Sprintf("+ %s (%s) will be installed", c.Name, desired)
+	case Upgrade:
+		return fmt.Sprintf("~ %s (%s -> %s) will be upgraded: %s", c.Name, c.Current.Chart, desired, c.Reason)
+	case Uninstall:
+		return fmt.Sprintf("- %s (%s) will be uninstalled", c.Name, c.Current.Chart)
+	default:
+		return fmt.Sprintf("= %s (%s) is up to date", c.Name, c.Current.Chart)
+	}
+}
+
+// Reconciler plans and applies manifests through an api client
+type Reconciler struct {
+	client api.Client
+}
+
+// New returns a reconciler for the client
+func New(client api.Client) *Reconciler {
+	return &Reconciler{client: client}
+}
+
+// Plan lists the releases of every target in the manifest and compares them with
+// the desired releases. A release is upgraded when it is not in the deployed state,
+// when its chart or version differs, or when its values differ from the deployed
+// values. Without a version in the manifest, the deployed version is kept
+func (r *Reconciler) Plan(ctx context.Context, m *Manifest) (*Plan, error) {
+	plan := &Plan{}
+	for _, target := range m.Targets {
+		deployed, err := r.client.List(ctx, flags.ListFlags{
+			Deployed: true,
+			Failed:   true,
+			Pending:  true,
+			CommonFlags: flags.CommonFlags{
+				KubeContext: target.KubeContext,
+				Namespace:   target.Namespace,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error listing releases in %s/%s: %s", target.KubeContext, target.Namespace, err)
+		}
+
+		current := map[string]*release.Release{}
+		for i := range deployed {
+			current[deployed[i].Name] = &deployed[i]
+		}
+
+		declared := map[string]bool{}
+		for _, rel := range target.Releases {
+			declared[rel.Name] = true
+			change, err := r.planRelease(ctx, m, target, rel, current[rel.Name])
+			if err != nil {
+				return nil, err
+			}
+			if change != nil {
+				plan.Changes = append(plan.Changes, *change)
+			}
+		}
+
+		if !target.Prune {
+			continue
+		}
+		for _, rel := range deployed {
+			if declared[rel.Name] {
+				continue
+			}
+			plan.Changes = append(plan.Changes, Change{
+				Type:        Uninstall,
+				KubeContext: target.KubeContext,
+				Namespace:   target.Namespace,
+				Name:        rel.Name,
+				Current:     current[rel.Name],
+			})
+		}
+	}
+	return plan, nil
+}
+
+func (r *Reconciler) planRelease(ctx context.Context, m *Manifest, target Target, rel Release, current *release.Release) (*Change, error) {
+	change := &Change{
+		KubeContext: target.KubeContext,
+		Namespace:   target.Namespace,
+		Name:        rel.Name,
+		Chart:       rel.Chart,
+		Version:     rel.Version,
+		Flags:       rel.Flags,
+		Current:     current,
+	}
+
+	if !rel.IsInstalled() {
+		if current == nil {
+			return nil, nil
+		}
+		change.Type = Uninstall
+		return change, nil
+	}
+
+	values, err := m.values(rel)
+	if err != nil {
+		return nil, err
+	}
+	change.Values = values
+
+	if current == nil {
+		change.Type = Install
+		return change, nil
+	}
+
+	// releases report their chart as <name>-<version>
+	deployedName, deployedVersion, _ := chart.SplitNameVersion(current.Chart)
+	sameChart := deployedName == chartName(rel.Chart)
+	if rel.Version == "" && sameChart {
+		change.Version = deployedVersion
+	}
+	switch {
+	case current.Status != "deployed":
+		change.Type = Upgrade
+		change.Reason = fmt.Sprintf("release is %s", current.Status)
+		return change, nil
+	case !sameChart:
+		change.Type = Upgrade
+		change.Reason = fmt.Sprintf("chart changed from %s", current.Chart)
+		return change, nil
+	case flags.IsVersionConstraint(rel.Version):
+		if !satisfies(current.Chart, rel.Chart, rel.Version) {
+			change.Type = Upgrade
+			change.Reason = "chart version does not satisfy the constraint"
+			return change, nil
+		}
+	case change.Version != deployedVersion:
+		change.Type = Upgrade
+		change.Reason = "chart version changed"
+		return change, nil
+	}
+
+	deployed, err := r.client.GetValues(ctx, rel.Name, flags.GetValuesFlags{
+		CommonFlags: flags.CommonFlags{
+			KubeContext: target.KubeContext,
+			Namespace:   target.Namespace,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting values of %s in %s/%s: %s", rel.Name, target.KubeContext, target.Namespace, err)
+	}
+	diff := deployed.Diff(values)
+	if len(diff) == 0 {
+		change.Type = Unchanged
+		return change, nil
+	}
+	changed := make([]string, len(diff))
+	for i, c := range diff {
+		changed[i] = c.String()
+	}
+	change.Type = Upgrade
+	change.Reason = "values changed: " + strings.Join(changed, ", ")
+	return change, nil
+}
+
+// chartName returns the name of a chart reference such as stable/nginx,
+// which is how the chart is reported in the release as <name>-<version>
+func chartName(chart string) string {
+	return path.Base(chart)
+}
+
+// satisfies reports whether the chart version of a deployed release satisfies the version constraint
+func satisfies(deployedChart string, desiredChart string, constraint string) bool {
+	name, version, ok := chart.SplitNameVersion(deployedChart)
+	if !ok || name != chartName(desiredChart) {
+		return false
+	}
+	matched, err := chart.Filter([]chart.Chart{{Version: version}}, constraint)
+	return err == nil && len(matched) == 1
+}
+
+// Apply runs the changes of the plan as a batch. Unchanged releases are left alone.
+// The options govern the concurrency and failure policy of the batch
+func (r *Reconciler) Apply(ctx context.Context, plan *Plan, opts ...batch.Option) (*batch.Report, error) {
+	var ops []batch.Operation
+	for _, change := range plan.Changes {
+		common := flags.CommonFlags{
+			KubeContext: change.KubeContext,
+			Namespace:   change.Namespace,
+		}
+		op := batch.Operation{
+			ID:     change.KubeContext + "/" + change.Namespace + "/" + change.Name,
+			Name:   change.Name,
+			Chart:  change.Chart,
+			Values: change.Values,
+		}
+
+		switch change.Type {
+		case Install:
+			op.Action = batch.Install
+			op.InstallFlags = flags.InstallFlags{
+				DryRun:      change.Flags.DryRun,
+				Version:     change.Version,
+				Wait:        change.Flags.Wait,
+				Atomic:      change.Flags.Atomic,
+				Timeout:     change.Flags.Timeout,
+				CommonFlags: common,
+			}
+		case Upgrade:
+			op.Action = batch.Upgrade
+			op.UpgradeFlags = flags.UpgradeFlags{
+				DryRun:      change.Flags.DryRun,
+				Version:     change.Version,
+				Wait:        change.Flags.Wait,
+				Atomic:      change.Flags.Atomic,
+				Timeout:     change.Flags.Timeout,
+				CommonFlags: common,
+			}
+		case Uninstall:
+			op.Action = batch.Uninstall
+			op.UninstallFlags = flags.UninstallFlags{CommonFlags: common}
+		default:
+			continue
+		}
+		ops = append(ops, op)
+	}
+
+	return batch.NewRunner(r.client, opts...).Run(ctx, ops)
+}
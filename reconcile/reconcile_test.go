@@ -0,0 +1,334 @@
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/gojekfarm/albatross-client-go/api/apitest"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/gojekfarm/albatross-client-go/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// listIn matches the flags of the list api for the releases of a target
+func listIn(kubeContext string, namespace string) interface{} {
+	return mock.MatchedBy(func(fl flags.ListFlags) bool {
+		return fl.KubeContext == kubeContext && fl.Namespace == namespace
+	})
+}
+
+const testManifest = `
+targets:
+  - kube_context: staging
+    namespace: apps
+    prune: true
+    releases:
+      - name: api
+        chart: charts/api
+        version: 1.2.0
+        values_files: [values/api.yaml]
+        values:
+          image:
+            tag: v2
+      - name: worker
+        chart: charts/worker
+        version: 1.0.0
+      - name: cache
+        chart: stable/redis
+        version: 10.0.0
+      - name: old-worker
+        installed: false
+  - kube_context: production
+    releases:
+      - name: api
+        chart: charts/api
+`
+
+const testValues = `
+replicas: 2
+image:
+  repository: api
+  tag: v1
+`
+
+func writeManifest(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "albatross-reconcile")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "values"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "values", "api.yaml"), []byte(testValues), 0600))
+	path := filepath.Join(dir, "releases.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(testManifest), 0600))
+	return path
+}
+
+// valuesIn matches the flags of the get values api for a release of a target
+func valuesIn(kubeContext string, namespace string) interface{} {
+	return mock.MatchedBy(func(fl flags.GetValuesFlags) bool {
+		return fl.KubeContext == kubeContext && fl.Namespace == namespace
+	})
+}
+
+func mockDeployedReleases(client *apitest.Client) {
+	client.On("List", listIn("staging", "apps")).Return([]release.Release{
+		{Name: "worker", Namespace: "apps", Chart: "worker-0.9.0", Status: "deployed"},
+		{Name: "cache", Namespace: "apps", Chart: "redis-10.0.0", Status: "deployed"},
+		{Name: "old-worker", Namespace: "apps", Chart: "worker-0.1.0", Status: "deployed"},
+		{Name: "manual", Namespace: "apps", Chart: "debug-0.1.0", Status: "failed"},
+	}, nil)
+	client.On("List", listIn("production", "default")).Return([]release.Release{
+		{Name: "api", Namespace: "default", Chart: "api-1.1.0", Status: "deployed"},
+	}, nil)
+	client.On("GetValues", "cache", valuesIn("staging", "apps")).Return(api.Values{}, nil)
+	client.On("GetValues", "api", valuesIn("production", "default")).Return(api.Values{"replicas": 3}, nil)
+}
+
+func TestReconcilerPlan(t *testing.T) {
+	m, err := LoadManifest(writeManifest(t))
+	require.NoError(t, err)
+	client := new(apitest.Client)
+	mockDeployedReleases(client)
+
+	plan, err := New(client).Plan(context.Background(), m)
+
+	require.NoError(t, err)
+	assert.True(t, plan.HasChanges())
+	assert.Equal(t, api.Values{
+		"replicas": 2,
+		"image":    map[string]interface{}{"repository": "api", "tag": "v2"},
+	}, plan.Changes[0].Values)
+	assert.Equal(t, `staging/apps:
+  + api (charts/api 1.2.0) will be installed
+  ~ worker (worker-0.9.0 -> charts/worker 1.0.0) will be upgraded: chart version changed
+  = cache (redis-10.0.0) is up to date
+  - old-worker (worker-0.1.0) will be uninstalled
+  - manual (debug-0.1.0) will be uninstalled
+production/default:
+  ~ api (api-1.1.0 -> charts/api 1.1.0) will be upgraded: values changed: - replicas: 3
+Plan: 1 to install, 2 to upgrade, 2 to uninstall, 1 unchanged.
+`, plan.String())
+}
+
+func TestReconcilerPlanKeepsNullValues(t *testing.T) {
+	path := writeManifest(t)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(filepath.Dir(path), "values", "api.yaml"), []byte(testValues+"resources: null\n"), 0600))
+	m, err := LoadManifest(path)
+	require.NoError(t, err)
+	client := new(apitest.Client)
+	mockDeployedReleases(client)
+
+	plan, err := New(client).Plan(context.Background(), m)
+
+	require.NoError(t, err)
+	// The null is sent to the server, where it deletes the default resources of the chart
+	assert.Equal(t, api.Values{
+		"replicas":  2,
+		"image":     map[string]interface{}{"repository": "api", "tag": "v2"},
+		"resources": nil,
+	}, plan.Changes[0].Values)
+}
+
+func TestReconcilerPlanDecryptsValuesFiles(t *testing.T) {
+	path := writeManifest(t)
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	encrypted := &bytes.Buffer{}
+	w, err := age.Encrypt(encrypted, identity.Recipient())
+	require.NoError(t, err)
+	_, err = w.Write([]byte(testValues))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, ioutil.WriteFile(filepath.Join(filepath.Dir(path), "values", "api.yaml"), encrypted.Bytes(), 0600))
+	m, err := LoadManifest(path)
+	require.NoError(t, err)
+	client := new(apitest.Client)
+	mockDeployedReleases(client)
+
+	_, err = New(client).Plan(context.Background(), m)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), secrets.ErrNoDecryptor.Error())
+
+	m.Decryptors = []secrets.Decryptor{secrets.NewAgeDecryptor(identity)}
+	plan, err := New(client).Plan(context.Background(), m)
+
+	require.NoError(t, err)
+	assert.Equal(t, api.Values{
+		"replicas": 2,
+		"image":    map[string]interface{}{"repository": "api", "tag": "v2"},
+	}, plan.Changes[0].Values)
+}
+
+func TestReconcilerPlanWithoutChanges(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("List", listIn("staging", "apps")).Return([]release.Release{
+		{Name: "cache", Chart: "redis-10.0.0", Status: "deployed"},
+		{Name: "manual", Chart: "debug-0.1.0", Status: "deployed"},
+	}, nil)
+	client.On("GetValues", "cache", valuesIn("staging", "apps")).Return(api.Values{"replicas": 2}, nil)
+	m := &Manifest{Targets: []Target{{
+		KubeContext: "staging",
+		Namespace:   "apps",
+		Releases: []Release{
+			{Name: "cache", Chart: "stable/redis", Version: "10.0.0", Values: map[string]interface{}{"replicas": 2}},
+		},
+	}}}
+
+	plan, err := New(client).Plan(context.Background(), m)
+
+	require.NoError(t, err)
+	assert.False(t, plan.HasChanges())
+}
+
+func TestReconcilerPlanUpgradesReleasesWhoseValuesChanged(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("List", listIn("staging", "apps")).Return([]release.Release{
+		{Name: "cache", Chart: "redis-10.0.0", Status: "deployed"},
+		{Name: "queue", Chart: "rabbitmq-6.0.0", Status: "deployed"},
+	}, nil)
+	client.On("GetValues", "cache", valuesIn("staging", "apps")).Return(api.Values{"replicas": 2}, nil)
+	client.On("GetValues", "queue", valuesIn("staging", "apps")).Return(api.Values{"replicas": 2}, nil)
+	m := &Manifest{Targets: []Target{{
+		KubeContext: "staging",
+		Namespace:   "apps",
+		Releases: []Release{
+			{Name: "cache", Chart: "stable/redis", Version: "10.0.0", Values: map[string]interface{}{"replicas": 3}},
+			{Name: "queue", Chart: "stable/rabbitmq", Values: map[string]interface{}{"replicas": 2}},
+		},
+	}}}
+
+	plan, err := New(client).Plan(context.Background(), m)
+
+	require.NoError(t, err)
+	require.Len(t, plan.Changes, 2)
+	assert.Equal(t, Upgrade, plan.Changes[0].Type)
+	assert.Equal(t, "values changed: ~ replicas: 2 -> 3", plan.Changes[0].Reason)
+	assert.Equal(t, "10.0.0", plan.Changes[0].Version)
+	assert.Equal(t, Unchanged, plan.Changes[1].Type)
+	assert.Equal(t, "6.0.0", plan.Changes[1].Version)
+}
+
+func TestReconcilerPlanWithVersionConstraints(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("List", listIn("staging", "apps")).Return([]release.Release{
+		{Name: "cache", Chart: "redis-10.2.0", Status: "deployed"},
+		{Name: "queue", Chart: "rabbitmq-6.0.0", Status: "deployed"},
+		{Name: "search", Chart: "elasticsearch-v7.10", Status: "deployed"},
+	}, nil)
+	client.On("GetValues", "cache", valuesIn("staging", "apps")).Return(api.Values{}, nil)
+	client.On("GetValues", "search", valuesIn("staging", "apps")).Return(api.Values{}, nil)
+	m := &Manifest{Targets: []Target{{
+		KubeContext: "staging",
+		Namespace:   "apps",
+		Releases: []Release{
+			{Name: "cache", Chart: "stable/redis", Version: "~10.2"},
+			{Name: "queue", Chart: "stable/rabbitmq", Version: ">=7.0 <8"},
+			{Name: "search", Chart: "elastic/elasticsearch", Version: "^7.10"},
+		},
+	}}}
+
+	plan, err := New(client).Plan(context.Background(), m)
+
+	require.NoError(t, err)
+	require.Len(t, plan.Changes, 3)
+	assert.Equal(t, Unchanged, plan.Changes[0].Type)
+	assert.Equal(t, Upgrade, plan.Changes[1].Type)
+	assert.Equal(t, "chart version does not satisfy the constraint", plan.Changes[1].Reason)
+	assert.Equal(t, Unchanged, plan.Changes[2].Type, "non-strict chart versions are compared too")
+}
+
+func TestReconcilerPlanUpgradesReleasesThatAreNotDeployed(t *testing.T) {
+	client := new(apitest.Client)
+	client.On("List", listIn("staging", "apps")).Return([]release.Release{
+		{Name: "cache", Chart: "redis-10.0.0", Status: "failed"},
+	}, nil)
+	m := &Manifest{Targets: []Target{{
+		KubeContext: "staging",
+		Namespace:   "apps",
+		Releases:    []Release{{Name: "cache", Chart: "stable/redis", Version: "10.0.0"}},
+	}}}
+
+	plan, err := New(client).Plan(context.Background(), m)
+
+	require.NoError(t, err)
+	require.Len(t, plan.Changes, 1)
+	assert.Equal(t, Upgrade, plan.Changes[0].Type)
+	assert.Equal(t, "release is failed", plan.Changes[0].Reason)
+}
+
+func TestReconcilerApply(t *testing.T) {
+	m, err := LoadManifest(writeManifest(t))
+	require.NoError(t, err)
+	client := new(apitest.Client)
+	mockDeployedReleases(client)
+	plan, err := New(client).Plan(context.Background(), m)
+	require.NoError(t, err)
+
+	staging := flags.CommonFlags{KubeContext: "staging", Namespace: "apps"}
+	client.On("Install", "api", "charts/api", plan.Changes[0].Values, flags.InstallFlags{Version: "1.2.0", CommonFlags: staging}).Return(api.Result{Status: "deployed"}, nil).Once()
+	client.On("Upgrade", "worker", "charts/worker", api.Values{}, flags.UpgradeFlags{Version: "1.0.0", CommonFlags: staging}).Return(api.Result{Status: "deployed"}, nil).Once()
+	client.On("Uninstall", "old-worker", flags.UninstallFlags{CommonFlags: staging}).Return(release.Release{}, nil).Once()
+	client.On("Uninstall", "manual", flags.UninstallFlags{CommonFlags: staging}).Return(release.Release{}, nil).Once()
+	client.On("Upgrade", "api", "charts/api", api.Values{}, flags.UpgradeFlags{
+		Version:     "1.1.0",
+		CommonFlags: flags.CommonFlags{KubeContext: "production", Namespace: "default"},
+	}).Return(api.Result{Status: "deployed"}, nil).Once()
+
+	report, err := New(client).Apply(context.Background(), plan)
+
+	require.NoError(t, err)
+	assert.True(t, report.Succeeded())
+	assert.Len(t, report.Results, 5)
+	client.AssertExpectations(t)
+}
+
+func TestLoadManifestErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "albatross-reconcile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name     string
+		manifest string
+		err      string
+	}{
+		{"missing kube context", "targets: [{releases: [{name: api, chart: charts/api}]}]", "kube context is a required parameter for every target"},
+		{"missing release name", "targets: [{kube_context: staging, releases: [{chart: charts/api}]}]", "release name cannot be empty in staging/default"},
+		{"invalid release name", "targets: [{kube_context: staging, releases: [{name: Api_v2, chart: charts/api}]}]", "invalid release name \"Api_v2\": must consist of lower case alphanumeric characters, '-' or '.', and start and end with an alphanumeric character in staging/default"},
+		{"missing chart", "targets: [{kube_context: staging, releases: [{name: api}]}]", "chart cannot be empty for release api in staging/default"},
+		{"duplicate release", "targets: [{kube_context: staging, releases: [{name: api, chart: a}, {name: api, chart: b}]}]", "duplicate release api in staging/default"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, "manifest.yaml")
+			require.NoError(t, ioutil.WriteFile(path, []byte(tc.manifest), 0600))
+			_, err := LoadManifest(path)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestManifestValidDoesNotSetDefaults(t *testing.T) {
+	m := Manifest{Targets: []Target{
+		{KubeContext: "staging", Releases: []Release{{Name: "api", Chart: "charts/api"}}},
+		{KubeContext: "staging", Namespace: "default", Releases: []Release{{Name: "api", Chart: "charts/api"}}},
+	}}
+
+	assert.EqualError(t, m.Valid(), "duplicate release api in staging/default")
+	assert.Equal(t, "", m.Targets[0].Namespace)
+
+	defaulted := m.WithDefaults()
+
+	assert.Equal(t, "default", defaulted.Targets[0].Namespace)
+	assert.Equal(t, "", m.Targets[0].Namespace)
+}
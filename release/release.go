@@ -15,3 +15,19 @@ type Release struct {
 	Chart      string    `json:"chart"`
 	AppVersion string    `json:"app_version"`
 }
+
+// TestResult is the outcome of a single test pod run by the release test api
+type TestResult struct {
+	Name      string    `json:"name"`
+	Phase     string    `json:"phase"`
+	Started   time.Time `json:"started_at,omitempty"`
+	Completed time.Time `json:"completed_at,omitempty"`
+
+	// Logs of the test pod, only returned when requested in the test flags
+	Logs string `json:"logs,omitempty"`
+}
+
+// Failed reports whether the test pod did not succeed
+func (t TestResult) Failed() bool {
+	return t.Phase != "Succeeded"
+}
@@ -0,0 +1,8 @@
+package repository
+
+// Repository represents a helm chart repository configured on the albatross server.
+// All apis that return a repository should return an instance of this struct
+type Repository struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
@@ -0,0 +1,369 @@
+// Package rollout upgrades a release in many clusters progressively, in ordered waves
+// such as a canary cluster first and the rest of the fleet after it, halting the
+// rollout as soon as a wave fails.
+package rollout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+)
+
+// ErrSkipped is wrapped by the error of every wave that was not run
+var ErrSkipped = errors.New("wave skipped")
+
+// Release is the release rolled out to every cluster. The kube context of the flags
+// is set per cluster, all other flags are shared
+type Release struct {
+	Name   string
+	Chart  string
+	Values api.Values
+	Flags  flags.UpgradeFlags
+}
+
+// Wave is a group of clusters that are upgraded at the same time
+type Wave struct {
+	Name         string
+	KubeContexts []string
+
+	// Pause is the time to wait after the wave succeeded, before the next wave starts.
+	// Defaults to the pause of the runner
+	Pause time.Duration
+}
+
+// ClusterResult is the outcome of the upgrade in a single cluster
+type ClusterResult struct {
+	KubeContext string
+
+	// Result of the upgrade. If the upgrade failed, Err is an *api.ErrUpgradeFailed
+	// that tells whether the cluster was rolled back
+	Result api.SafeUpgradeResult
+	Err    error
+
+	// PreviousRevision is the revision deployed before the rollout, zero if the release was installed
+	PreviousRevision int
+
+	// RolledBack is set if the cluster was rolled back to the previous revision because
+	// a later cluster failed. RollbackErr is set if that rollback failed
+	RolledBack  bool
+	RollbackErr error
+
+	Started  time.Time
+	Duration time.Duration
+}
+
+// WaveResult is the outcome of a wave
+type WaveResult struct {
+	Wave     Wave
+	Clusters []ClusterResult
+
+	// Err is set if the wave was not run
+	Err error
+
+	Started  time.Time
+	Duration time.Duration
+}
+
+// Skipped reports whether the wave was not run
+func (w WaveResult) Skipped() bool {
+	return errors.Is(w.Err, ErrSkipped)
+}
+
+// Failed returns the results of the clusters that failed to upgrade
+func (w WaveResult) Failed() []ClusterResult {
+	var failed []ClusterResult
+	for _, cluster := range w.Clusters {
+		if cluster.Err != nil {
+			failed = append(failed, cluster)
+		}
+	}
+	return failed
+}
+
+// Report holds the results of all waves, in order
+type Report struct {
+	Waves []WaveResult
+}
+
+// Succeeded reports whether all waves were run and all clusters upgraded
+func (r *Report) Succeeded() bool {
+	for _, wave := range r.Waves {
+		if wave.Err != nil || len(wave.Failed()) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the results of the clusters that failed to upgrade
+func (r *Report) Failed() []ClusterResult {
+	var failed []ClusterResult
+	for _, wave := range r.Waves {
+		failed = append(failed, wave.Failed()...)
+	}
+	return failed
+}
+
+// Option represents the contract of a runner modifier function
+type Option func(runner *Runner)
+
+// WithConcurrency sets the maximum number of clusters of a wave upgraded at the same time
+func WithConcurrency(concurrency int) Option {
+	return func(runner *Runner) {
+		runner.concurrency = concurrency
+	}
+}
+
+// WithPause sets the time to wait between waves, unless a wave sets its own
+func WithPause(pause time.Duration) Option {
+	return func(runner *Runner) {
+		runner.pause = pause
+	}
+}
+
+// WithRollbackOnFailure rolls back the clusters that were already upgraded when a wave fails,
+// so that all clusters end up on the revision deployed before the rollout
+func WithRollbackOnFailure() Option {
+	return func(runner *Runner) {
+		runner.rollbackOnFailure = true
+	}
+}
+
+// WithRollbackTimeout sets how long to wait for the rollback of a cluster, by api.SafeUpgrade
+// or after a failure with WithRollbackOnFailure. Rollbacks are not bound to the context of
+// Run, so that they are done even when it is cancelled. Defaults to api.DefaultRollbackTimeout.
+// With Wait, the timeout of the flags is used instead if it is longer
+func WithRollbackTimeout(timeout time.Duration) Option {
+	return func(runner *Runner) {
+		runner.rollbackTimeout = timeout
+		runner.upgradeOpts = append(runner.upgradeOpts, api.WithRollbackTimeout(timeout))
+	}
+}
+
+// WithStatusTimeout sets how long to wait for the release to be deployed in a cluster
+func WithStatusTimeout(timeout time.Duration) Option {
+	return func(runner *Runner) {
+		runner.upgradeOpts = append(runner.upgradeOpts, api.WithStatusTimeout(timeout))
+	}
+}
+
+// WithStatusPollInterval sets how often the status of the release is polled in a cluster
+func WithStatusPollInterval(interval time.Duration) Option {
+	return func(runner *Runner) {
+		runner.upgradeOpts = append(runner.upgradeOpts, api.WithStatusPollInterval(interval))
+	}
+}
+
+// Runner rolls out releases through an api client
+type Runner struct {
+	client            api.Client
+	concurrency       int
+	pause             time.Duration
+	rollbackOnFailure bool
+	rollbackTimeout   time.Duration
+	upgradeOpts       []api.SafeUpgradeOption
+}
+
+// NewRunner returns a runner that upgrades 5 clusters of a wave at the same time, does not
+// pause between waves and does not roll back completed waves, unless configured otherwise
+// by the options
+func NewRunner(client api.Client, opts ...Option) *Runner {
+	runner := &Runner{client: client, concurrency: 5, rollbackTimeout: api.DefaultRollbackTimeout}
+	for _, opt := range opts {
+		opt(runner)
+	}
+	if runner.concurrency <= 0 {
+		runner.concurrency = 1
+	}
+	return runner
+}
+
+// Run upgrades the release in the clusters of a wave at the same time, up to the concurrency
+// of the runner, with api.SafeUpgrade, which waits for the release to be deployed and rolls back the clusters that fail.
+// The next wave starts once all clusters of the wave succeeded and the pause is over.
+// After a failure the rollout halts, the remaining waves are skipped, and with
+// WithRollbackOnFailure the upgraded clusters are rolled back, latest wave first.
+// When ctx is done the rollout halts the same way, including the rollback, and ctx.Err()
+// is returned along with the Report. Without WithRollbackOnFailure, a cancelled rollout
+// leaves the clusters of the completed waves upgraded and the rest on the previous revision.
+// An error is returned without running anything if the rollout is invalid. Failures
+// of single clusters are only reported in the Report
+func (r *Runner) Run(ctx context.Context, rel Release, waves []Wave) (*Report, error) {
+	if err := validate(rel, waves); err != nil {
+		return nil, err
+	}
+
+	report := &Report{Waves: make([]WaveResult, len(waves))}
+	halted := -1
+	for i, wave := range waves {
+		if ctx.Err() != nil {
+			halted = i
+			break
+		}
+
+		report.Waves[i] = r.runWave(ctx, rel, wave)
+		if len(report.Waves[i].Failed()) > 0 {
+			halted = i + 1
+			break
+		}
+
+		if i < len(waves)-1 {
+			pause := wave.Pause
+			if pause == 0 {
+				pause = r.pause
+			}
+			if err := sleep(ctx, pause); err != nil {
+				halted = i + 1
+				break
+			}
+		}
+	}
+	if halted < 0 {
+		return report, nil
+	}
+
+	reason := fmt.Errorf("%w: rollout was halted after a failure", ErrSkipped)
+	if ctx.Err() != nil {
+		reason = fmt.Errorf("%w: %s", ErrSkipped, ctx.Err())
+	}
+	for i := halted; i < len(waves); i++ {
+		report.Waves[i] = WaveResult{Wave: waves[i], Err: reason}
+	}
+
+	if r.rollbackOnFailure {
+		r.rollback(rel, report)
+	}
+	return report, ctx.Err()
+}
+
+// runWave upgrades the clusters of a wave concurrently, up to the concurrency of the runner
+func (r *Runner) runWave(ctx context.Context, rel Release, wave Wave) WaveResult {
+	result := WaveResult{Wave: wave, Clusters: make([]ClusterResult, len(wave.KubeContexts)), Started: time.Now()}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, r.concurrency)
+	for i, kubeContext := range wave.KubeContexts {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, kubeContext string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			result.Clusters[i] = r.upgrade(ctx, rel, kubeContext)
+		}(i, kubeContext)
+	}
+	wg.Wait()
+
+	result.Duration = time.Since(result.Started)
+	return result
+}
+
+func (r *Runner) upgrade(ctx context.Context, rel Release, kubeContext string) ClusterResult {
+	result := ClusterResult{KubeContext: kubeContext, Started: time.Now()}
+
+	fl := rel.Flags
+	fl.KubeContext = kubeContext
+	opts := append(r.upgradeOpts[:len(r.upgradeOpts):len(r.upgradeOpts)], api.WithSafeUpgradeHooks(api.SafeUpgradeHooks{
+		BeforeUpgrade: func(previous *release.Release) {
+			if previous != nil {
+				result.PreviousRevision = previous.Version
+			}
+		},
+	}))
+	result.Result, result.Err = api.SafeUpgrade(ctx, r.client, rel.Name, rel.Chart, rel.Values, fl, opts...)
+
+	result.Duration = time.Since(result.Started)
+	return result
+}
+
+// rollback rolls the clusters that were upgraded back to their previous revision,
+// latest wave first. Clusters that failed were already rolled back by api.SafeUpgrade.
+// Every rollback runs on its own context with the rollback timeout, or the timeout of the
+// flags with Wait if it is longer
+func (r *Runner) rollback(rel Release, report *Report) {
+	timeout := r.rollbackTimeout
+	if rel.Flags.Wait && time.Duration(rel.Flags.Timeout)*time.Second > timeout {
+		timeout = time.Duration(rel.Flags.Timeout) * time.Second
+	}
+	for i := len(report.Waves) - 1; i >= 0; i-- {
+		clusters := report.Waves[i].Clusters
+		for j := len(clusters) - 1; j >= 0; j-- {
+			cluster := &clusters[j]
+			if cluster.Err != nil {
+				continue
+			}
+			if cluster.PreviousRevision == 0 {
+				cluster.RollbackErr = errors.New("release was installed by the rollout, there is no previous revision")
+				continue
+			}
+
+			common := rel.Flags.CommonFlags
+			common.KubeContext = cluster.KubeContext
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			_, cluster.RollbackErr = r.client.Rollback(ctx, rel.Name, flags.RollbackFlags{
+				Revision:      cluster.PreviousRevision,
+				Wait:          rel.Flags.Wait,
+				Timeout:       rel.Flags.Timeout,
+				CleanupOnFail: rel.Flags.CleanupOnFail,
+				CommonFlags:   common,
+			})
+			cancel()
+			cluster.RolledBack = cluster.RollbackErr == nil
+		}
+	}
+}
+
+// validate checks the rollout before anything is run
+func validate(rel Release, waves []Wave) error {
+	if err := flags.ValidReleaseName(rel.Name); err != nil {
+		return err
+	}
+	if rel.Chart == "" {
+		return errors.New("chart cannot be empty")
+	}
+	if len(waves) == 0 {
+		return errors.New("rollout has no waves")
+	}
+
+	seen := map[string]bool{}
+	for i, wave := range waves {
+		if len(wave.KubeContexts) == 0 {
+			return fmt.Errorf("wave %d has no clusters", i+1)
+		}
+		if wave.Pause < 0 {
+			return fmt.Errorf("pause of wave %d cannot be negative", i+1)
+		}
+		for _, kubeContext := range wave.KubeContexts {
+			if kubeContext == "" {
+				return fmt.Errorf("wave %d has an empty kube context", i+1)
+			}
+			if seen[kubeContext] {
+				return fmt.Errorf("duplicate cluster %s in wave %d", kubeContext, i+1)
+			}
+			seen[kubeContext] = true
+		}
+	}
+	return nil
+}
+
+// sleep waits for the duration, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,278 @@
+package rollout
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/gojekfarm/albatross-client-go/api/apitest"
+	"github.com/gojekfarm/albatross-client-go/flags"
+	"github.com/gojekfarm/albatross-client-go/release"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// in matches the flags of a call to the kube context
+func in(kubeContext string) interface{} {
+	return mock.MatchedBy(func(fl interface{}) bool {
+		switch fl := fl.(type) {
+		case flags.UpgradeFlags:
+			return fl.KubeContext == kubeContext
+		case flags.StatusFlags:
+			return fl.KubeContext == kubeContext
+		case flags.HistoryFlags:
+			return fl.KubeContext == kubeContext
+		case flags.RollbackFlags:
+			return fl.KubeContext == kubeContext
+		}
+		return false
+	})
+}
+
+// rollbackTo matches the flags of a rollback in the kube context to the revision
+func rollbackTo(kubeContext string, revision int) interface{} {
+	return mock.MatchedBy(func(fl flags.RollbackFlags) bool {
+		return fl.KubeContext == kubeContext && fl.Revision == revision
+	})
+}
+
+// deployed sets up a cluster whose release is on revision 2 and is upgraded to the status
+func deployed(client *apitest.Client, kubeContext string, status string) {
+	client.On("History", "api", in(kubeContext)).Return([]release.Release{{Name: "api", Version: 2, Status: "deployed"}}, nil)
+	client.On("Upgrade", "api", "charts/api", mock.Anything, in(kubeContext)).Return(api.Result{Status: "pending-upgrade", Version: "1.3.0"}, nil)
+	client.On("Status", "api", in(kubeContext)).Return(release.Release{Name: "api", Version: 3, Status: status}, nil)
+}
+
+// upgraded returns the kube contexts the release was upgraded in, in order
+func upgraded(client *apitest.Client) []string {
+	var kubeContexts []string
+	for _, call := range client.Calls {
+		if call.Method == "Upgrade" {
+			kubeContexts = append(kubeContexts, call.Arguments.Get(3).(flags.UpgradeFlags).KubeContext)
+		}
+	}
+	return kubeContexts
+}
+
+var testRelease = Release{
+	Name:   "api",
+	Chart:  "charts/api",
+	Values: api.Values{"replicas": 2},
+	Flags:  flags.UpgradeFlags{Version: "1.3.0"},
+}
+
+func fastRunner(client api.Client, opts ...Option) *Runner {
+	return NewRunner(client, append([]Option{WithStatusPollInterval(time.Millisecond), WithStatusTimeout(time.Second)}, opts...)...)
+}
+
+func TestRunnerRollsOutWavesInOrder(t *testing.T) {
+	client := new(apitest.Client)
+	for _, kubeContext := range []string{"canary", "eu-1", "eu-2", "us-1"} {
+		deployed(client, kubeContext, "deployed")
+	}
+	waves := []Wave{
+		{Name: "canary", KubeContexts: []string{"canary"}, Pause: 20 * time.Millisecond},
+		{Name: "10%", KubeContexts: []string{"eu-1"}},
+		{Name: "rest", KubeContexts: []string{"eu-2", "us-1"}},
+	}
+
+	started := time.Now()
+	report, err := fastRunner(client).Run(context.Background(), testRelease, waves)
+
+	require.NoError(t, err)
+	assert.True(t, report.Succeeded())
+	assert.GreaterOrEqual(t, int64(time.Since(started)), int64(20*time.Millisecond))
+	assert.Equal(t, []string{"canary", "eu-1"}, upgraded(client)[:2])
+	assert.ElementsMatch(t, []string{"eu-2", "us-1"}, upgraded(client)[2:])
+	require.Len(t, report.Waves[2].Clusters, 2)
+	assert.Equal(t, "us-1", report.Waves[2].Clusters[1].KubeContext)
+	assert.Equal(t, 2, report.Waves[2].Clusters[1].PreviousRevision)
+	assert.Equal(t, "deployed", report.Waves[2].Clusters[1].Result.Release.Status)
+}
+
+func TestRunnerBoundsTheConcurrencyOfAWave(t *testing.T) {
+	client := new(apitest.Client)
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	kubeContexts := []string{"eu-1", "eu-2", "eu-3", "us-1", "us-2", "us-3"}
+	for _, kubeContext := range kubeContexts {
+		client.On("History", "api", in(kubeContext)).Return([]release.Release{{Name: "api", Version: 2, Status: "deployed"}}, nil)
+		client.On("Upgrade", "api", "charts/api", mock.Anything, in(kubeContext)).Run(func(mock.Arguments) {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}).Return(api.Result{Status: "deployed", Version: "1.3.0"}, nil)
+		client.On("Status", "api", in(kubeContext)).Return(release.Release{Name: "api", Version: 3, Status: "deployed"}, nil)
+	}
+
+	report, err := fastRunner(client, WithConcurrency(2)).Run(context.Background(), testRelease, []Wave{{KubeContexts: kubeContexts}})
+
+	require.NoError(t, err)
+	assert.True(t, report.Succeeded())
+	assert.Equal(t, 2, maxRunning)
+}
+
+func TestRunnerHaltsAfterAFailedWave(t *testing.T) {
+	client := new(apitest.Client)
+	deployed(client, "canary", "deployed")
+	deployed(client, "eu-1", "failed")
+	deployed(client, "eu-2", "deployed")
+	client.On("Rollback", "api", rollbackTo("eu-1", 2)).Return(release.Release{Version: 4, Status: "deployed"}, nil)
+	waves := []Wave{
+		{KubeContexts: []string{"canary"}},
+		{KubeContexts: []string{"eu-1", "eu-2"}},
+		{KubeContexts: []string{"us-1"}},
+	}
+
+	report, err := fastRunner(client).Run(context.Background(), testRelease, waves)
+
+	require.NoError(t, err)
+	assert.False(t, report.Succeeded())
+	failed := report.Failed()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "eu-1", failed[0].KubeContext)
+	assert.EqualError(t, failed[0].Err, "upgrade of release api failed: release is failed; rolled back to revision 2")
+	assert.True(t, report.Waves[2].Skipped())
+	assert.EqualError(t, report.Waves[2].Err, "wave skipped: rollout was halted after a failure")
+	assert.False(t, report.Waves[0].Clusters[0].RolledBack)
+	client.AssertNotCalled(t, "Upgrade", mock.Anything, mock.Anything, mock.Anything, in("us-1"))
+	client.AssertNotCalled(t, "Rollback", mock.Anything, in("canary"))
+}
+
+func TestRunnerRollsBackCompletedWavesOnFailure(t *testing.T) {
+	client := new(apitest.Client)
+	deployed(client, "canary", "deployed")
+	deployed(client, "eu-1", "failed")
+	deployed(client, "eu-2", "deployed")
+	client.On("Rollback", "api", rollbackTo("eu-1", 2)).Return(release.Release{Version: 4, Status: "deployed"}, nil)
+	client.On("Rollback", "api", rollbackTo("eu-2", 2)).Return(release.Release{Version: 4, Status: "deployed"}, nil)
+	client.On("Rollback", "api", rollbackTo("canary", 2)).Return(release.Release{}, errors.New("Rollback API returned an error: cluster unreachable"))
+	waves := []Wave{
+		{KubeContexts: []string{"canary"}},
+		{KubeContexts: []string{"eu-1", "eu-2"}},
+	}
+
+	report, err := fastRunner(client, WithRollbackOnFailure()).Run(context.Background(), testRelease, waves)
+
+	require.NoError(t, err)
+	assert.True(t, report.Waves[1].Clusters[1].RolledBack)
+	assert.False(t, report.Waves[1].Clusters[0].RolledBack, "failed clusters are rolled back by the upgrade")
+	assert.False(t, report.Waves[0].Clusters[0].RolledBack)
+	assert.EqualError(t, report.Waves[0].Clusters[0].RollbackErr, "Rollback API returned an error: cluster unreachable")
+	client.AssertExpectations(t)
+}
+
+func TestRunnerStopsWhenTheContextIsDone(t *testing.T) {
+	client := new(apitest.Client)
+	deployed(client, "canary", "deployed")
+	ctx, cancel := context.WithCancel(context.Background())
+	waves := []Wave{
+		{KubeContexts: []string{"canary"}, Pause: time.Minute},
+		{KubeContexts: []string{"eu-1"}},
+	}
+
+	time.AfterFunc(20*time.Millisecond, cancel)
+	report, err := fastRunner(client).Run(ctx, testRelease, waves)
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, report.Failed())
+	assert.EqualError(t, report.Waves[1].Err, "wave skipped: context canceled")
+}
+
+// contextClient fails rollbacks whose context is done, as the http client does
+type contextClient struct {
+	*apitest.Client
+}
+
+func (c contextClient) Rollback(ctx context.Context, name string, fl flags.RollbackFlags) (release.Release, error) {
+	if err := ctx.Err(); err != nil {
+		return release.Release{}, err
+	}
+	return c.Client.Rollback(ctx, name, fl)
+}
+
+func TestRunnerRollsBackCompletedWavesWhenTheContextIsDone(t *testing.T) {
+	client := new(apitest.Client)
+	deployed(client, "canary", "deployed")
+	client.On("Rollback", "api", rollbackTo("canary", 2)).Return(release.Release{Version: 4, Status: "deployed"}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	waves := []Wave{
+		{KubeContexts: []string{"canary"}, Pause: time.Minute},
+		{KubeContexts: []string{"eu-1"}},
+	}
+
+	time.AfterFunc(20*time.Millisecond, cancel)
+	report, err := fastRunner(contextClient{client}, WithRollbackOnFailure(), WithRollbackTimeout(time.Second)).Run(ctx, testRelease, waves)
+
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, report.Waves[0].Clusters[0].RolledBack)
+	assert.NoError(t, report.Waves[0].Clusters[0].RollbackErr)
+	assert.EqualError(t, report.Waves[1].Err, "wave skipped: context canceled")
+	client.AssertExpectations(t)
+}
+
+// deadlineClient records the deadline of the rollback context
+type deadlineClient struct {
+	*apitest.Client
+	deadline time.Time
+}
+
+func (c *deadlineClient) Rollback(ctx context.Context, name string, fl flags.RollbackFlags) (release.Release, error) {
+	c.deadline, _ = ctx.Deadline()
+	return c.Client.Rollback(ctx, name, fl)
+}
+
+func TestRunnerGivesRollbacksTheTimeoutOfTheFlagsWithWait(t *testing.T) {
+	client := &deadlineClient{Client: new(apitest.Client)}
+	deployed(client.Client, "canary", "deployed")
+	deployed(client.Client, "eu-1", "failed")
+	client.On("Rollback", "api", mock.Anything).Return(release.Release{Version: 4, Status: "deployed"}, nil)
+	rel := testRelease
+	rel.Flags.Wait = true
+	rel.Flags.Timeout = 900
+	waves := []Wave{{KubeContexts: []string{"canary"}}, {KubeContexts: []string{"eu-1"}}}
+
+	started := time.Now()
+	report, err := fastRunner(client, WithRollbackOnFailure()).Run(context.Background(), rel, waves)
+
+	require.NoError(t, err)
+	assert.True(t, report.Waves[0].Clusters[0].RolledBack)
+	assert.WithinDuration(t, started.Add(15*time.Minute), client.deadline, time.Second)
+}
+
+func TestRunnerRejectsInvalidRollouts(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rel   Release
+		waves []Wave
+		err   string
+	}{
+		{"no chart", Release{Name: "api"}, []Wave{{KubeContexts: []string{"canary"}}}, "chart cannot be empty"},
+		{"no waves", testRelease, nil, "rollout has no waves"},
+		{"empty wave", testRelease, []Wave{{KubeContexts: []string{"canary"}}, {}}, "wave 2 has no clusters"},
+		{"duplicate cluster", testRelease, []Wave{{KubeContexts: []string{"canary"}}, {KubeContexts: []string{"eu-1", "canary"}}}, "duplicate cluster canary in wave 2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := new(apitest.Client)
+
+			report, err := NewRunner(client).Run(context.Background(), tc.rel, tc.waves)
+
+			assert.Nil(t, report)
+			assert.EqualError(t, err, tc.err)
+			client.AssertNotCalled(t, "Upgrade", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
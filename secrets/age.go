@@ -0,0 +1,72 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// ageIntro starts the header of binary age files
+const ageIntro = "age-encryption.org/v1\n"
+
+// IsAge reports whether the data is an age encrypted file, in binary or armored form
+func IsAge(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(ageIntro)) ||
+		bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(armor.Header))
+}
+
+// AgeDecryptor decrypts files encrypted as a whole with age (https://age-encryption.org),
+// with one or more identities. Files encrypted by sops with age keys are not age files
+// and are not decrypted
+type AgeDecryptor struct {
+	identities []age.Identity
+}
+
+// NewAgeDecryptor returns a decryptor for files encrypted for any of the identities
+func NewAgeDecryptor(identities ...age.Identity) *AgeDecryptor {
+	return &AgeDecryptor{identities: identities}
+}
+
+// LoadIdentityFile returns a decryptor for the identities in an age identity file,
+// as generated by age-keygen
+func LoadIdentityFile(path string) (*AgeDecryptor, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading identity file: %s", err)
+	}
+	defer file.Close()
+
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing identity file %s: %s", path, err)
+	}
+	return NewAgeDecryptor(identities...), nil
+}
+
+// Encrypted reports whether the data is an age encrypted file
+func (d *AgeDecryptor) Encrypted(data []byte) bool {
+	return IsAge(data)
+}
+
+// Decrypt returns the plain content of an age encrypted file
+func (d *AgeDecryptor) Decrypt(data []byte) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(data)
+	if !bytes.HasPrefix(data, []byte(ageIntro)) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimLeft(data, " \t\r\n")))
+	}
+
+	plain, err := age.Decrypt(src, d.identities...)
+	if err != nil {
+		return nil, fmt.Errorf("Error decrypting the values: %s", err)
+	}
+	data, err = ioutil.ReadAll(plain)
+	if err != nil {
+		return nil, fmt.Errorf("Error decrypting the values: %s", err)
+	}
+	return data, nil
+}
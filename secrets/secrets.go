@@ -0,0 +1,60 @@
+// Package secrets decrypts values files that are kept encrypted, e.g. in git, before
+// they are merged into the values of a release. Decryptors are pluggable; the built-in
+// one reads files encrypted with age (https://age-encryption.org).
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/gojekfarm/albatross-client-go/api"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoDecryptor is returned for encrypted data that none of the decryptors can decrypt
+var ErrNoDecryptor = errors.New("no decryptor for encrypted values")
+
+// Decryptor decrypts values files in a specific format
+type Decryptor interface {
+	// Encrypted reports whether the data is encrypted in the format of the decryptor
+	Encrypted(data []byte) bool
+
+	// Decrypt returns the plain content of encrypted data
+	Decrypt(data []byte) ([]byte, error)
+}
+
+// Decrypt decrypts the data with the first decryptor that recognizes its format.
+// Data that is not encrypted in any of the formats is returned as is, except for
+// age and sops encrypted files, which fail with ErrNoDecryptor
+func Decrypt(data []byte, decryptors ...Decryptor) ([]byte, error) {
+	for _, decryptor := range decryptors {
+		if decryptor.Encrypted(data) {
+			return decryptor.Decrypt(data)
+		}
+	}
+	if IsAge(data) || IsSops(data) {
+		return nil, ErrNoDecryptor
+	}
+	return data, nil
+}
+
+// ReadValuesFile reads a yaml values file, decrypting it first if it is encrypted
+func ReadValuesFile(path string, decryptors ...Decryptor) (api.Values, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading values file: %s", err)
+	}
+
+	plain, err := Decrypt(data, decryptors...)
+	if err != nil {
+		return nil, fmt.Errorf("Error decrypting values file %s: %s", path, err)
+	}
+
+	values := api.Values{}
+	if err := yaml.Unmarshal(bytes.TrimSpace(plain), &values); err != nil {
+		return nil, fmt.Errorf("Error parsing values file %s: %s", path, err)
+	}
+	return values, nil
+}
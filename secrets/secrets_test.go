@@ -0,0 +1,188 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/gojekfarm/albatross-client-go/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const plainValues = `
+database:
+  password: s3cret
+replicas: 2
+`
+
+func generateIdentity(t *testing.T) *age.X25519Identity {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	return identity
+}
+
+// encrypt encrypts the values for the identities, in armored form if armored is set
+func encrypt(t *testing.T, armored bool, identities ...*age.X25519Identity) []byte {
+	var recipients []age.Recipient
+	for _, identity := range identities {
+		recipients = append(recipients, identity.Recipient())
+	}
+
+	out := &bytes.Buffer{}
+	var dst io.WriteCloser = nopCloser{out}
+	if armored {
+		dst = armor.NewWriter(out)
+	}
+	w, err := age.Encrypt(dst, recipients...)
+	require.NoError(t, err)
+	_, err = w.Write([]byte(plainValues))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, dst.Close())
+	return out.Bytes()
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "albatross-secrets")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestAgeDecryptor(t *testing.T) {
+	alice, bob := generateIdentity(t), generateIdentity(t)
+
+	for _, armored := range []bool{false, true} {
+		encrypted := encrypt(t, armored, alice, bob)
+		assert.True(t, IsAge(encrypted))
+		assert.NotContains(t, string(encrypted), "s3cret")
+
+		for _, identity := range []*age.X25519Identity{alice, bob} {
+			plain, err := Decrypt(encrypted, NewAgeDecryptor(identity))
+
+			require.NoError(t, err)
+			assert.Equal(t, plainValues, string(plain))
+		}
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	identity := generateIdentity(t)
+	encrypted := encrypt(t, false, identity)
+
+	t.Run("When the values are not encrypted for the identity", func(t *testing.T) {
+		_, err := Decrypt(encrypted, NewAgeDecryptor(generateIdentity(t)))
+
+		assert.EqualError(t, err, "Error decrypting the values: no identity matched any of the recipients")
+	})
+
+	t.Run("When the values were tampered with", func(t *testing.T) {
+		tampered := append([]byte{}, encrypted...)
+		tampered[len(tampered)-1] ^= 1
+
+		_, err := Decrypt(tampered, NewAgeDecryptor(identity))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("When there is no decryptor", func(t *testing.T) {
+		_, err := Decrypt(encrypted)
+
+		assert.Equal(t, ErrNoDecryptor, err)
+	})
+}
+
+func TestDecryptRejectsSopsFiles(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{"yaml", "image:\n    tag: ENC[AES256_GCM,data:dj4=,iv:aXY=,tag:dGFn,type:str]\nsops:\n    version: 3.8.1\n"},
+		{"json", `{"image": {"tag": "ENC[AES256_GCM,data:dj4=,iv:aXY=,tag:dGFn,type:str]"}, "sops": {"version": "3.8.1"}}`},
+		{"metadata only", "replicas: 2\nsops:\n    mac: ENC[AES256_GCM,data:bWFj,type:str]\n    version: 3.8.1\n"},
+		{"encrypted values only", "password: ENC[AES256_GCM,data:cGFzcw==,type:str]\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decrypt([]byte(tc.data), NewAgeDecryptor(generateIdentity(t)))
+
+			assert.Equal(t, ErrNoDecryptor, err)
+		})
+	}
+}
+
+func TestDecryptReturnsPlainValuesAsIs(t *testing.T) {
+	plain, err := Decrypt([]byte(plainValues), NewAgeDecryptor(generateIdentity(t)))
+
+	require.NoError(t, err)
+	assert.Equal(t, plainValues, string(plain))
+}
+
+// reverseDecryptor is a decryptor for a toy format, to test pluggable decryptors
+type reverseDecryptor struct{}
+
+func (reverseDecryptor) Encrypted(data []byte) bool {
+	return len(data) > 0 && data[0] == '!'
+}
+
+func (reverseDecryptor) Decrypt(data []byte) ([]byte, error) {
+	if len(data) == 1 {
+		return nil, errors.New("empty")
+	}
+	plain := make([]byte, 0, len(data)-1)
+	for i := len(data) - 1; i > 0; i-- {
+		plain = append(plain, data[i])
+	}
+	return plain, nil
+}
+
+func TestDecryptUsesTheDecryptorOfTheFormat(t *testing.T) {
+	plain, err := Decrypt([]byte("!1 :a"), NewAgeDecryptor(generateIdentity(t)), reverseDecryptor{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "a: 1", string(plain))
+}
+
+func TestLoadIdentityFile(t *testing.T) {
+	identity := generateIdentity(t)
+	dir := tempDir(t)
+	path := filepath.Join(dir, "keys.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte("# created: 2020-01-01\n"+identity.String()+"\n"), 0600))
+
+	decryptor, err := LoadIdentityFile(path)
+	require.NoError(t, err)
+	plain, err := decryptor.Decrypt(encrypt(t, true, identity))
+
+	require.NoError(t, err)
+	assert.Equal(t, plainValues, string(plain))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("not a key\n"), 0600))
+	_, err = LoadIdentityFile(path)
+	assert.Contains(t, err.Error(), "Error parsing identity file "+path)
+}
+
+func TestReadValuesFile(t *testing.T) {
+	identity := generateIdentity(t)
+	path := filepath.Join(tempDir(t), "secrets.yaml")
+	require.NoError(t, ioutil.WriteFile(path, encrypt(t, true, identity), 0600))
+
+	values, err := ReadValuesFile(path, NewAgeDecryptor(identity))
+
+	require.NoError(t, err)
+	assert.Equal(t, api.Values{
+		"database": api.Values{"password": "s3cret"},
+		"replicas": 2,
+	}, values)
+}
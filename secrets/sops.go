@@ -0,0 +1,25 @@
+package secrets
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sopsValue starts the values encrypted by sops, e.g. ENC[AES256_GCM,data:...,type:str]
+const sopsValue = "ENC[AES256_GCM,"
+
+// IsSops reports whether the data is a yaml or json file encrypted by sops
+// (https://github.com/getsops/sops). Such files keep their keys in plain text, but hold
+// encrypted values and the sops metadata under a top-level sops key
+func IsSops(data []byte) bool {
+	if bytes.Contains(data, []byte(sopsValue)) {
+		return true
+	}
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return false
+	}
+	_, ok := document["sops"].(map[string]interface{})
+	return ok
+}